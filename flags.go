@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ohait/jl/tbuf"
+)
+
+// a flag that can be repeated
+type stringsFlag []string
+
+func (this *stringsFlag) String() string {
+	return strings.Join(*this, ", ")
+}
+
+func (this *stringsFlag) Set(s string) error {
+	*this = append(*this, s)
+	return nil
+}
+
+var joiner = &tbuf.Joiner{}
+
+var (
+	flagCont     = flag.String("cont", "", "join lines matching `regexp` to the previous record (e.g. '^(\\s|at )')")
+	flagStart    = flag.String("start", "", "only lines matching `regexp` start a new record")
+	flagStartTS  = flag.Bool("start-ts", false, "only lines starting with a timestamp start a new record")
+	flagStack    = flag.Bool("stack", false, "join java and python stack traces to the previous record")
+	flagJSON     = flag.Bool("multijson", false, "join multi-line (pretty printed) json documents")
+	flagANSI     = flag.Bool("R", false, "render the colors of ansi escape sequences (they are stripped otherwise)")
+	flagTZ       = flag.String("tz", "Local", "time `zone` for timestamps without one (e.g. UTC, Europe/Oslo)")
+	flagLevels   = flag.String("level-order", "", "comma separated `levels`, from the least to the most severe")
+	flagLayouts  stringsFlag
+	flagLevelMap stringsFlag
+)
+
+func init() {
+	flag.Var(&flagLayouts, "time-layout", "additional go time `layout` to parse timestamps (can be repeated)")
+	flag.Var(&flagLevelMap, "level-map", "map levels as `alias=level[,alias=level...]` (can be repeated)")
+}
+
+func parseFlags() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [file]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	loc, err := time.LoadLocation(*flagTZ)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -tz: %v\n", err)
+		os.Exit(2)
+	}
+	tbuf.Location = loc
+	tbuf.TimeLayouts = flagLayouts
+
+	if *flagLevels != "" {
+		tbuf.SetLevelOrder(strings.Split(*flagLevels, ","))
+	}
+	for _, m := range flagLevelMap {
+		for _, kv := range strings.Split(m, ",") {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) != 2 {
+				fmt.Fprintf(os.Stderr, "invalid -level-map: %q\n", kv)
+				os.Exit(2)
+			}
+			tbuf.MapLevel(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+		}
+	}
+
+	joiner.JSON = *flagJSON
+	if *flagStack {
+		joiner.Continue = tbuf.ReStackTrace
+	}
+	if *flagCont != "" {
+		joiner.Continue = mustCompile("cont", *flagCont)
+	}
+	if *flagStartTS {
+		joiner.Start = tbuf.ReStartsWithTime
+	}
+	if *flagStart != "" {
+		joiner.Start = mustCompile("start", *flagStart)
+	}
+}
+
+func mustCompile(name, p string) *regexp.Regexp {
+	re, err := regexp.Compile(p)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -%s: %v\n", name, err)
+		os.Exit(2)
+	}
+	return re
+}
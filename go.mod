@@ -5,8 +5,8 @@ go 1.15
 require (
 	github.com/gdamore/tcell v1.4.0
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
-	github.com/mattn/go-runewidth v0.0.14 // indirect
-	github.com/rivo/uniseg v0.4.4 // indirect
+	github.com/mattn/go-runewidth v0.0.14
+	github.com/rivo/uniseg v0.4.4
 	golang.org/x/sys v0.6.0
 	golang.org/x/text v0.8.0 // indirect
 )
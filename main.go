@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"io"
 	"os"
@@ -21,10 +22,12 @@ var buffer = &tbuf.Buffer{}
 var scr *screen.Screen
 
 func main() {
+	parseFlags()
 	log("INIT #######################################################")
 	defer log("EXIT")
 
 	scr = screen.NewScreen(log, buffer)
+	scr.ANSI = *flagANSI
 	defer scr.Close()
 
 	sigchan := make(chan os.Signal, 10)
@@ -43,12 +46,9 @@ LOOP:
 
 		case <-t.C:
 			t.Reset(50 * time.Millisecond)
-			if buffer.Last != last {
-				last = buffer.Last
-				scr.Repaint()
-			} else if scr.Refresh {
-				scr.Repaint()
-			}
+			changed := buffer.Last != last
+			last = buffer.Last
+			scr.Tick(changed)
 
 		case sig := <-sigchan:
 			log("SIG: %v", sig)
@@ -94,7 +94,7 @@ func IsTerminal(fd uintptr) bool {
 }
 
 func readInit() {
-	if len(os.Args) == 1 {
+	if flag.NArg() == 0 {
 		if !IsTerminal(os.Stdin.Fd()) {
 			// only slurp stdin if not a terminal
 			go read(os.Stdin, "STDIN")
@@ -104,7 +104,7 @@ func readInit() {
 			return
 		}
 	} else {
-		f, err := os.Open(os.Args[1])
+		f, err := os.Open(flag.Arg(0))
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "%v\n", err)
 			return
@@ -118,12 +118,42 @@ func read(f io.Reader, fname string) {
 	r := bufio.NewReader(f)
 	log("reading... %q", fname)
 	defer log("done reading")
+	lines := make(chan string, 100)
+	go func() {
+		defer close(lines)
+		for {
+			l, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			util.Chop(&l) // remove trailing \n
+			lines <- l
+		}
+	}()
+	gotest := &tbuf.GoTest{}
+	emit := func(s string) {
+		buffer.Add(gotest.Feed(tbuf.ParseLines(s, log))...)
+	}
+	idle := time.NewTimer(time.Hour)
 	for {
-		l, err := r.ReadString('\n')
-		if err != nil {
-			return
+		select {
+		case l, ok := <-lines:
+			if !ok {
+				joiner.Flush(emit)
+				buffer.Add(gotest.Flush()...)
+				return
+			}
+			joiner.Push(l, emit)
+			if !idle.Stop() {
+				select {
+				case <-idle.C:
+				default:
+				}
+			}
+			idle.Reset(200 * time.Millisecond)
+		case <-idle.C:
+			// nothing new for a while, don't hold back the last record
+			joiner.Idle(emit)
 		}
-		util.Chop(&l) // remove trailing \n
-		buffer.Append(l, log)
 	}
 }
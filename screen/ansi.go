@@ -0,0 +1,149 @@
+package screen
+
+import (
+	"github.com/gdamore/tcell"
+	"github.com/ohait/jl/util"
+	"github.com/rivo/uniseg"
+)
+
+// PrintANSI prints s rendering its SGR sequences, skipping the first bytes of
+// visible text, and highlighting the rest like PrintHL
+func (this Cursor) PrintANSI(s string, skip int) Cursor {
+	orig := this.Style
+	spans := hlSpans(util.StripANSI(s)[skip:], 0, this.highlights())
+	pos := 0 // in the visible text, after skip
+	util.SplitANSI(s, func(text string, sgr []int) {
+		if sgr != nil {
+			this.Style = applySGR(this.Style, orig, sgr)
+			return
+		}
+		if skip >= len(text) {
+			skip -= len(text)
+			return
+		}
+		text, skip = text[skip:], 0
+		st := this.Style
+		for len(text) > 0 {
+			n := len(text)
+			this.Style = st
+			for _, sp := range spans {
+				switch {
+				case sp.from <= pos && pos < sp.to:
+					this.Style = this.Style.Foreground(sp.h.fg).Bold(sp.h.bold)
+					if sp.to-pos < n {
+						n = sp.to - pos
+					}
+				case pos < sp.from && sp.from-pos < n:
+					n = sp.from - pos
+				}
+			}
+			this = this.print(text[:n], false)
+			text, pos = text[n:], pos+n
+		}
+		this.Style = st
+	})
+	this.Style = orig
+	return this
+}
+
+// a part of the text highlighted by a layer
+type hlSpan struct {
+	from, to int
+	h        highlight
+}
+
+// where printLayers would highlight s: the first layer, and the others within
+// what is left and within its matches. Outer spans come before the inner ones
+func hlSpans(s string, off int, hl []highlight) (out []hlSpan) {
+	if len(hl) == 0 {
+		return nil
+	}
+	h := hl[0]
+	inner := func(from, to int) {
+		out = append(out, hlSpans(s[from:to], off+from, hl[1:])...)
+	}
+	i := 0
+	for {
+		p := h.re.FindStringIndex(s[i:])
+		if p == nil {
+			break
+		}
+		if p[0] == p[1] { // empty match, move on by a char
+			cl, _, _, _ := uniseg.FirstGraphemeClusterInString(s[i+p[0]:], -1)
+			if cl == "" {
+				break
+			}
+			inner(i, i+p[0]+len(cl))
+			i += p[0] + len(cl)
+			continue
+		}
+		inner(i, i+p[0])
+		out = append(out, hlSpan{off + i + p[0], off + i + p[1], h})
+		inner(i+p[0], i+p[1])
+		i += p[1]
+	}
+	inner(i, len(s))
+	return out
+}
+
+func applySGR(st, base tcell.Style, p []int) tcell.Style {
+	fg, bg, _ := base.Decompose()
+	for i := 0; i < len(p); i++ {
+		switch n := p[i]; {
+		case n == 0:
+			st = base
+		case n == 1:
+			st = st.Bold(true)
+		case n == 2:
+			st = st.Dim(true)
+		case n == 3:
+			st = st.Italic(true)
+		case n == 4:
+			st = st.Underline(true)
+		case n == 5:
+			st = st.Blink(true)
+		case n == 7:
+			st = st.Reverse(true)
+		case n == 22:
+			st = st.Bold(false).Dim(false)
+		case n == 23:
+			st = st.Italic(false)
+		case n == 24:
+			st = st.Underline(false)
+		case n == 25:
+			st = st.Blink(false)
+		case n == 27:
+			st = st.Reverse(false)
+		case n >= 30 && n <= 37:
+			st = st.Foreground(tcell.Color(n - 30))
+		case n == 39:
+			st = st.Foreground(fg)
+		case n >= 40 && n <= 47:
+			st = st.Background(tcell.Color(n - 40))
+		case n == 49:
+			st = st.Background(bg)
+		case n >= 90 && n <= 97:
+			st = st.Foreground(tcell.Color(n - 90 + 8))
+		case n >= 100 && n <= 107:
+			st = st.Background(tcell.Color(n - 100 + 8))
+		case n == 38 || n == 48: // 256 colors or rgb
+			var col tcell.Color
+			switch {
+			case i+2 < len(p) && p[i+1] == 5:
+				col = tcell.Color(p[i+2])
+				i += 2
+			case i+4 < len(p) && p[i+1] == 2:
+				col = tcell.NewRGBColor(int32(p[i+2]), int32(p[i+3]), int32(p[i+4]))
+				i += 4
+			default:
+				return st
+			}
+			if n == 38 {
+				st = st.Foreground(col)
+			} else {
+				st = st.Background(col)
+			}
+		}
+	}
+	return st
+}
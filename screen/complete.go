@@ -0,0 +1,154 @@
+package screen
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell"
+	"github.com/ohait/jl/tbuf"
+)
+
+// how many of the last lines are looked at for completions
+const completeScan = 20000
+
+// max completions shown in the popup
+const completeShow = 10
+
+// a field name, or field=value, before the cursor
+var reCompleteToken = regexp.MustCompile(`[\w.@$-]*(=[^\s=]*)?$`)
+
+// complete returns the field names starting with token, or the most frequent
+// values if token is field=..., quoted so the search finds them as they are
+func complete(b *tbuf.Buffer, token string) []string {
+	field, prefix, isValue := "", token, false
+	if i := strings.IndexByte(token, '='); i >= 0 {
+		field, prefix, isValue = token[:i], token[i+1:], true
+	}
+	count := map[string]int{}
+	size := b.Size()
+	for _, l := range b.Slice(size-completeScan, size) {
+		if !isValue {
+			for k := range l.Tags {
+				if strings.HasPrefix(k, prefix) {
+					count[k]++
+				}
+			}
+			continue
+		}
+		j, ok := l.Tags[field]
+		if !ok || len(j) == 0 || j[0] == '{' || j[0] == '[' {
+			continue
+		}
+		v := string(j)
+		var s string
+		if json.Unmarshal(j, &s) == nil {
+			v = s
+		}
+		q := regexp.QuoteMeta(v)
+		if len(q) <= 60 && !strings.ContainsAny(v, "\n") && (strings.HasPrefix(v, prefix) || strings.HasPrefix(q, prefix)) {
+			count[field+"="+q]++
+		}
+	}
+	out := make([]string, 0, len(count))
+	for k := range count {
+		out = append(out, k)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if count[out[i]] != count[out[j]] {
+			return count[out[i]] > count[out[j]]
+		}
+		return out[i] < out[j]
+	})
+	return out
+}
+
+func commonPrefix(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+	p := list[0]
+	for _, s := range list[1:] {
+		for !strings.HasPrefix(s, p) {
+			_, n := utf8.DecodeLastRuneInString(p) // whole runes, not to leave half of one
+			p = p[:len(p)-n]
+		}
+	}
+	return p
+}
+
+// tab in the query, complete or cycle thru the completions
+func (this *Screen) onTab(dir int) {
+	if this.query != "SEARCH" {
+		return
+	}
+	in := this.input.Get()
+	if len(this.completions) > 0 {
+		n := len(this.completions)
+		prev := this.compToken
+		if this.compSel >= 0 {
+			prev = this.completions[this.compSel]
+		}
+		this.compSel = (this.compSel + dir + n + 1) % (n + 1)
+		if this.compSel == n { // back to what was typed
+			this.compSel = -1
+			in.ReplaceBefore(prev, this.compToken)
+		} else {
+			in.ReplaceBefore(prev, this.completions[this.compSel])
+		}
+		this.onChange()
+		return
+	}
+
+	l, _ := in.Details()
+	token := reCompleteToken.FindString(l)
+	list := complete(this.buffer, token)
+	switch len(list) {
+	case 0:
+	case 1:
+		c := list[0]
+		if !strings.Contains(c, "=") {
+			c += "="
+		}
+		in.ReplaceBefore(token, c)
+		this.onChange()
+	default:
+		p := commonPrefix(list)
+		in.ReplaceBefore(token, p)
+		this.completions = list
+		this.compToken = p
+		this.compSel = -1
+		this.onChange()
+	}
+}
+
+// the completions, above the status bar
+func (this *Screen) paintCompletions() {
+	if len(this.completions) == 0 {
+		return
+	}
+	_, h := this.scr.Size()
+	list := this.completions
+	more := 0
+	if len(list) > completeShow {
+		more = len(list) - completeShow
+		list = list[:completeShow]
+	}
+	y := h - 1 - len(list)
+	if more > 0 {
+		y--
+	}
+	st := tcell.StyleDefault.Background(tcell.Color237).Foreground(tcell.Color222)
+	for i, c := range list {
+		cur := this.NewCursor(2, y+i)
+		cur.Style = st.Reverse(i == this.compSel)
+		cur.Printf(" %-30s ", c)
+	}
+	if more > 0 {
+		cur := this.NewCursor(2, h-2)
+		cur.Style = st.Dim(true)
+		cur.Printf(" … %d more%-22s ", more, "")
+	}
+}
@@ -0,0 +1,48 @@
+package screen
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ohait/jl/tbuf"
+)
+
+func TestComplete(t *testing.T) {
+	b := &tbuf.Buffer{}
+	b.Append(`{"msg":"a","user":"bob","url":"/x"}`, t.Log)
+	b.Append(`{"msg":"b","user":"alice","status":200}`, t.Log)
+	b.Append(`{"msg":"c","user":"alice","status":500}`, t.Log)
+	b.Append(`{"msg":"d","url":"/x?a=1"}`, t.Log)
+
+	if got, exp := complete(b, "u"), []string{"user", "url"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+	if got, exp := complete(b, "user="), []string{"user=alice", "user=bob"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+	if got, exp := complete(b, "status=5"), []string{"status=500"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+	if got, exp := complete(b, "url=/x?"), []string{`url=/x\?a=1`}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+	// what is completed is a query finding the line it came from
+	for _, c := range []string{"user=bob", `url=/x\?a=1`, "status=500"} {
+		q := tbuf.ParseQuery(c, tbuf.CaseSensitive)
+		if b.Find(0, b.Size(), q.Match) < 0 {
+			t.Errorf("%q not found", c)
+		}
+		if q.Match(b.Lines[1]) {
+			t.Errorf("%q matches %q", c, b.Lines[1].Str)
+		}
+	}
+	if got := commonPrefix([]string{"user=alice", "user=al", "user=bob"}); got != "user=" {
+		t.Errorf("bad common prefix %q", got)
+	}
+	b.Append(`{"msg":"e","city":"café"}`, t.Log)
+	b.Append(`{"msg":"f","city":"cafè"}`, t.Log)
+	list := complete(b, "city=caf")
+	if got := commonPrefix(list); len(list) != 2 || got != "city=caf" {
+		t.Errorf("bad common prefix %q of %q", got, list)
+	}
+}
@@ -7,16 +7,21 @@ import (
 	"time"
 
 	"github.com/gdamore/tcell"
+	"github.com/mattn/go-runewidth"
 	"github.com/ohait/jl/tbuf"
+	"github.com/ohait/jl/util"
+	"github.com/rivo/uniseg"
 )
 
 func (this *Screen) NewCursor(x, y int) Cursor {
 	return Cursor{
-		scr:     this,
-		X:       x,
-		Y:       y,
-		pattern: this.pattern,
-		Style:   tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.Color234),
+		scr:   this,
+		X:     x,
+		Y:     y,
+		last:  x,
+		query: this.pattern,
+		pins:  this.pins,
+		Style: tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.Color234),
 	}
 }
 
@@ -26,11 +31,15 @@ type Cursor struct {
 	Y       int
 	Offset  int
 	Style   tcell.Style
-	pattern *regexp.Regexp
+	query   *tbuf.Query
+	pattern *regexp.Regexp // what to highlight of the query, in what is being printed
+	pins    []*pin
+	last    int // where the last char was printed
 }
 
 func (this Cursor) CR(x int) Cursor {
 	this.X = x
+	this.last = x
 	if this.Offset > 0 {
 		this.Offset--
 	} else {
@@ -49,18 +58,36 @@ func (this Cursor) Printf(f string, args ...interface{}) Cursor {
 
 var reKeywords = regexp.MustCompile(`\b((?i)error|err|panic|closed?|invalid)\b`)
 
+type highlight struct {
+	re   *regexp.Regexp
+	fg   tcell.Color
+	bold bool
+}
+
+// the search, then the pinned patterns, then the keywords
+func (this Cursor) highlights() (out []highlight) {
+	if this.pattern != nil {
+		out = append(out, highlight{this.pattern, tcell.Color87, true})
+	}
+	for _, p := range this.pins {
+		out = append(out, highlight{p.q.Re, p.color, true})
+	}
+	return append(out, highlight{reKeywords, tcell.Color173, false})
+}
+
 func (this Cursor) PrintHL(s string) Cursor {
-	if this.pattern == nil {
-		return this.printHL(s, reKeywords, this.Style.Foreground(tcell.Color173), func(this Cursor, s string) Cursor {
-			return this.print(s, true)
-		})
-	} else {
-		return this.printHL(s, this.pattern, this.Style.Foreground(tcell.Color87).Bold(true), func(this Cursor, s string) Cursor {
-			return this.printHL(s, reKeywords, this.Style.Foreground(tcell.Color173), func(this Cursor, s string) Cursor {
-				return this.print(s, true)
-			})
-		})
+	return this.printLayers(s, this.highlights())
+}
+
+// highlight the first layer, and the others within what is left
+func (this Cursor) printLayers(s string, hl []highlight) Cursor {
+	if len(hl) == 0 {
+		return this.print(s, true)
 	}
+	h := hl[0]
+	return this.printHL(s, h.re, this.Style.Foreground(h.fg).Bold(h.bold), func(this Cursor, s string) Cursor {
+		return this.printLayers(s, hl[1:])
+	})
 }
 
 func (this Cursor) printHL(s string, p *regexp.Regexp, st tcell.Style, pfunc func(Cursor, string) Cursor) Cursor {
@@ -70,6 +97,15 @@ func (this Cursor) printHL(s string, p *regexp.Regexp, st tcell.Style, pfunc fun
 		if pairs == nil {
 			break
 		}
+		if pairs[0] == pairs[1] { // empty match, move on by a char
+			cl, _, _, _ := uniseg.FirstGraphemeClusterInString(s[pairs[0]:], -1)
+			if cl == "" {
+				break
+			}
+			this = pfunc(this, s[:pairs[0]+len(cl)])
+			s = s[pairs[0]+len(cl):]
+			continue
+		}
 		this = pfunc(this, s[0:pairs[0]])
 		this.Style = st
 		this = pfunc(this, s[pairs[0]:pairs[1]])
@@ -88,18 +124,44 @@ func (this Cursor) print(s string, dim bool) Cursor {
 	if this.Offset > 0 {
 		return this
 	}
-	for _, ch := range s {
-		switch ch {
-		case '"', '\'', '(', '{', '}', ')', ',', ':', '[', ']', '/':
-			this.scr.scr.SetContent(this.X, this.Y, ch, nil, this.Style.Dim(dim))
-		case '\t':
-			this.scr.scr.SetContent(this.X, this.Y, '⇥', nil, this.Style.Bold(true).Foreground(tcell.Color220))
-		case '\n':
-			this.scr.scr.SetContent(this.X, this.Y, '␍', nil, this.Style.Bold(true).Foreground(tcell.Color220))
+	w, _ := this.scr.scr.Size()
+	state := -1
+	for len(s) > 0 {
+		var cl string
+		cl, s, _, state = uniseg.FirstGraphemeClusterInString(s, state)
+		rs := []rune(cl)
+		st := this.Style
+		cw := runewidth.StringWidth(cl) // same width tcell will use
+		switch ch := rs[0]; {
+		case strings.ContainsRune(`"'(){},:[]/`, ch):
+			st = st.Dim(dim)
+		case ch == '\t':
+			rs, cw = []rune{'⇥'}, 1
+			st = st.Bold(true).Foreground(tcell.Color220)
+		case ch == '\n' || ch == '\r':
+			rs, cw = []rune{'␍'}, 1
+			st = st.Bold(true).Foreground(tcell.Color220)
+		case ch < 0x20:
+			rs, cw = []rune{0x2400 + ch}, 1 // control pictures
+			st = st.Bold(true).Foreground(tcell.Color220)
+		case cw == 0: // combining marks split from their base, e.g. by highlighting
+			if this.last < this.X {
+				mainc, combc, st, _ := this.scr.scr.GetContent(this.last, this.Y)
+				this.scr.scr.SetContent(this.last, this.Y, mainc, append(combc, rs...), st)
+			}
+			continue
+		}
+		switch {
+		case this.X < 0 && this.X+cw > 0, this.X < w && this.X+cw > w:
+			// wide char across the edges, only show what fits
+			for x := this.X; x < this.X+cw; x++ {
+				this.scr.scr.SetContent(x, this.Y, ' ', nil, st)
+			}
 		default:
-			this.scr.scr.SetContent(this.X, this.Y, ch, nil, this.Style)
+			this.scr.scr.SetContent(this.X, this.Y, rs[0], rs[1:], st)
 		}
-		this.X++
+		this.last = this.X
+		this.X += cw
 	}
 	return this
 }
@@ -130,6 +192,7 @@ func (this Cursor) Fill(r rune) Cursor {
 		this.scr.scr.SetContent(this.X, this.Y, r, nil, this.Style)
 	}
 	this.X = 0
+	this.last = 0
 	this.Y++
 	return this
 }
@@ -167,6 +230,8 @@ func (this Cursor) ColByLevel(l string) Cursor {
 	}
 	var col tcell.Color
 	switch strings.ToLower(l) {
+	case "trace":
+		col = tcell.Color240
 	case "debug":
 		col = tcell.Color66
 	case "info":
@@ -177,6 +242,8 @@ func (this Cursor) ColByLevel(l string) Cursor {
 		col = tcell.ColorYellow
 	case "error":
 		col = tcell.ColorRed
+	case "fatal":
+		col = tcell.ColorFuchsia
 	default:
 		return this
 	}
@@ -191,6 +258,8 @@ func (this Cursor) Level(l string) Cursor {
 	fg, bg, _ := this.Style.Decompose()
 	var label string
 	switch strings.ToLower(l) {
+	case "trace":
+		label = "trc"
 	case "debug":
 		label = "dbg"
 	case "info":
@@ -201,16 +270,27 @@ func (this Cursor) Level(l string) Cursor {
 		label = "WRN"
 	case "error":
 		label = "ERR"
+	case "fatal":
+		label = "FTL"
 	default:
 		label = l
 	}
+	label = runewidth.FillRight(label, 3) // keep the column aligned
 	this = this.ColByLevel(l).print(label, false).Col(fg, bg)
 	return this
 }
 
 func (this Cursor) Line(l tbuf.Line, padding int) Cursor {
-	if l.Mark {
-		this.Style = this.Style.Background(tcell.Color52)
+	if l.Divider {
+		w, _ := this.scr.scr.Size()
+		this.Style = this.Style.Foreground(tcell.Color240)
+		return this.Print(strings.Repeat("─", w)).Clear()
+	}
+	if l.Marks != 0 {
+		this.Style = this.Style.Background(markColor(l.Marks))
+	}
+	if l.Context {
+		this.Style = this.Style.Dim(true).Foreground(tcell.Color244)
 	}
 	fg, _, _ := this.Style.Decompose()
 	if !l.Time.IsZero() {
@@ -219,10 +299,19 @@ func (this Cursor) Line(l tbuf.Line, padding int) Cursor {
 	}
 	this = this.Level(l.Level)
 	this = this.Print(" ")
-	if l.Short != "" {
-		this = this.PrintHL(l.Short)
-	} else {
-		this = this.PrintHL(l.Str)
+	if l.Source != "" {
+		this = this.Fg(tcell.Color109).Print(l.Source).Fg(fg).Print(": ")
+	}
+	msg := l.Short
+	if msg == "" {
+		msg = l.Str
+	}
+	this.pattern = this.query.Highlight(tbuf.PartMessage, "")
+	if this.scr.ANSI && l.ANSI != "" {
+		// the whole line, or the message of a json line
+		if plain := util.StripANSI(l.ANSI); strings.HasSuffix(plain, msg) {
+			return this.PrintANSI(l.ANSI, len(plain)-len(msg)).Clear()
+		}
 	}
-	return this.Clear()
+	return this.PrintHL(msg).Clear()
 }
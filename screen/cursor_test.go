@@ -0,0 +1,130 @@
+package screen
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/ohait/jl/tbuf"
+)
+
+func simScreen(t *testing.T, w, h int) *Screen {
+	sim := tcell.NewSimulationScreen("UTF-8")
+	if err := sim.Init(); err != nil {
+		t.Fatal(err)
+	}
+	sim.SetSize(w, h)
+	return &Screen{scr: sim}
+}
+
+// the content of a row, with wide chars continuation as ""
+func row(s *Screen, y int) (out []string) {
+	w, _ := s.scr.Size()
+	for x := 0; x < w; x++ {
+		mainc, combc, _, width := s.scr.GetContent(x, y)
+		out = append(out, string(append([]rune{mainc}, combc...)))
+		for ; width > 1; width-- {
+			out = append(out, "")
+			x++
+		}
+	}
+	return
+}
+
+func TestPrintWide(t *testing.T) {
+	s := simScreen(t, 10, 2)
+	cur := s.NewCursor(0, 0).Print("a日本é")
+	if cur.X != 6 {
+		t.Fatalf("expected X=6, got %d", cur.X)
+	}
+	got := row(s, 0)
+	exp := []string{"a", "日", "", "本", "", "é", " "}
+	for i, e := range exp {
+		if got[i] != e {
+			t.Fatalf("expected %q, got %q", exp, got)
+		}
+	}
+
+	// scrolled horizontally, half of a wide char is cut out
+	s.NewCursor(-2, 1).Print("a日本")
+	got = row(s, 1)
+	if got[0] != " " || got[1] != "本" {
+		t.Fatalf("bad left edge: %q", got)
+	}
+}
+
+func TestPrintHLCombining(t *testing.T) {
+	s := simScreen(t, 10, 1)
+	cur := s.NewCursor(0, 0)
+	cur.pattern = regexp.MustCompile(`e`)
+	cur = cur.PrintHL("néx")
+	if cur.X != 3 {
+		t.Fatalf("expected X=3, got %d", cur.X)
+	}
+	if got := row(s, 0); got[1] != "é" || got[2] != "x" {
+		t.Fatalf("combining mark not attached: %q", got)
+	}
+
+	cur = s.NewCursor(0, 0)
+	cur.pattern = regexp.MustCompile(`x*`)
+	cur = cur.PrintHL("日ab") // empty matches must not loop forever
+	if cur.X != 4 {
+		t.Fatalf("expected X=4, got %d", cur.X)
+	}
+}
+
+func TestLineHighlightScope(t *testing.T) {
+	s := simScreen(t, 40, 1)
+	l := tbuf.ParseLine(`{"message":"id 42","id":"42"}`, t.Log)
+	fg := func(x int) tcell.Color {
+		_, _, st, _ := s.scr.GetContent(x, 0)
+		c, _, _ := st.Decompose()
+		return c
+	}
+	for q, hl := range map[string]bool{"m:42": true, "v:42": true, "k:id": false, "id=42": false, "42": true} {
+		s.pattern = tbuf.ParseQuery(q, tbuf.CaseSensitive)
+		s.NewCursor(0, 0).Line(l, 0)
+		x := strings.Index(strings.Join(row(s, 0), ""), "42")
+		if got := fg(x) == tcell.Color87; got != hl {
+			t.Errorf("%q: expected highlighted %v, got %v", q, hl, got)
+		}
+	}
+}
+
+func TestLineContext(t *testing.T) {
+	s := simScreen(t, 10, 2)
+	s.NewCursor(0, 0).Line(tbuf.Line{Str: "--", Divider: true}, 0)
+	if got := row(s, 0); got[0] != "─" || got[9] != "─" {
+		t.Fatalf("expected a divider, got %q", got)
+	}
+	s.NewCursor(0, 1).Line(tbuf.Line{Str: "ctx", Context: true}, 0)
+	_, _, st, _ := s.scr.GetContent(4, 1)
+	if _, _, attr := st.Decompose(); attr&tcell.AttrDim == 0 {
+		t.Fatalf("expected context lines dimmed")
+	}
+}
+
+func TestLineANSI(t *testing.T) {
+	s := simScreen(t, 40, 2)
+	s.ANSI = true
+	l := tbuf.ParseLine(`{"msg":"\u001b[31mred\u001b[0m timeout err"}`, t.Log)
+	cur := s.NewCursor(0, 0)
+	cur.query = tbuf.ParseQuery(`ed t`, tbuf.CaseSensitive)
+	cur.Line(l, 0)
+
+	text := strings.Join(row(s, 0), "")
+	x := strings.Index(text, "red timeout err")
+	if x < 0 {
+		t.Fatalf("message not shown: %q", text)
+	}
+	fg := func(i int) tcell.Color {
+		_, _, st, _ := s.scr.GetContent(x+i, 0)
+		c, _, _ := st.Decompose()
+		return c
+	}
+	red, _, _ := applySGR(tcell.StyleDefault, tcell.StyleDefault, []int{31}).Decompose()
+	if fg(0) != red || fg(1) != tcell.Color87 || fg(4) != tcell.Color87 || fg(5) == tcell.Color87 || fg(12) != tcell.Color173 {
+		t.Fatalf("bad colors: %v %v %v %v %v", fg(0), fg(1), fg(4), fg(5), fg(12))
+	}
+}
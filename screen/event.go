@@ -3,8 +3,8 @@ package screen
 import (
 	"errors"
 	"fmt"
-	"regexp"
 	"strings"
+	"time"
 
 	"github.com/gdamore/tcell"
 	"github.com/ohait/jl/tbuf"
@@ -17,6 +17,10 @@ func (this *Screen) event(ev tcell.Event) error {
 	this.help = false
 	switch ev := ev.(type) {
 	case *tcell.EventKey:
+		this.status = ""
+		if this.menu != nil {
+			return this.eventMenu(ev)
+		}
 		if this.query != "" { // user query
 			return this.eventQuery(ev)
 		} else {
@@ -32,17 +36,80 @@ func (this *Screen) event(ev tcell.Event) error {
 
 func (this *Screen) eventQuery(ev *tcell.EventKey) error {
 	this.log("eventQuery(%+v)", ev)
+	if this.rsearch != nil {
+		return this.eventReverseSearch(ev)
+	}
+	switch ev.Key() {
+	case tcell.KeyTab, tcell.KeyBacktab:
+	default:
+		this.completions = nil
+	}
 	switch ev.Key() {
+	case tcell.KeyTab:
+		this.onTab(1)
+		this.Repaint()
+	case tcell.KeyBacktab:
+		this.onTab(-1)
+		this.Repaint()
+	case tcell.KeyEscape, tcell.KeyCtrlG: // cancel
+		this.query = ""
+		this.Repaint()
+	case tcell.KeyCtrlR:
+		if this.query == "SEARCH" {
+			this.rsearch = &util.Input{}
+			this.rsPos = this.input.Pos
+			this.rsOrig = this.input.Get().String()
+			this.Repaint()
+		}
 	case tcell.KeyLeft:
 		this.input.Get().Left()
 		this.Repaint()
 	case tcell.KeyRight:
 		this.input.Get().Right()
 		this.Repaint()
+	case tcell.KeyHome, tcell.KeyCtrlA:
+		this.input.Get().Home()
+		this.Repaint()
+	case tcell.KeyEnd, tcell.KeyCtrlE:
+		this.input.Get().End()
+		this.Repaint()
+	case tcell.KeyCtrlB:
+		this.input.Get().Left()
+		this.Repaint()
+	case tcell.KeyCtrlF:
+		this.input.Get().Right()
+		this.Repaint()
 	case tcell.KeyBackspace, tcell.KeyBackspace2:
 		this.input.Get().Backspace()
 		this.onChange()
 		this.Repaint()
+	case tcell.KeyDelete, tcell.KeyCtrlD:
+		this.input.Get().Delete()
+		this.onChange()
+		this.Repaint()
+	case tcell.KeyCtrlW:
+		this.input.Get().DeleteWord()
+		this.onChange()
+		this.Repaint()
+	case tcell.KeyCtrlU:
+		this.input.Get().KillLeft()
+		this.onChange()
+		this.Repaint()
+	case tcell.KeyCtrlK:
+		this.input.Get().KillRight()
+		this.onChange()
+		this.Repaint()
+	case tcell.KeyCtrlV: // paste
+		s, err := util.ClipPaste()
+		if err != nil {
+			this.status = fmt.Sprintf("paste error: %v", err)
+			this.Repaint()
+			break
+		}
+		s = strings.TrimRight(s, "\r\n")
+		this.input.Get().Append(strings.NewReplacer("\r\n", " ", "\n", " ").Replace(s))
+		this.onChange()
+		this.Repaint()
 	case tcell.KeyUp:
 		this.input.Up()
 		this.onChange()
@@ -60,7 +127,18 @@ func (this *Screen) eventQuery(ev *tcell.EventKey) error {
 		this.Repaint()
 	case tcell.KeyRune:
 		this.log("query char: %q", ev.Rune())
-		switch ev.Rune() {
+		switch {
+		case ev.Modifiers()&tcell.ModAlt != 0 && ev.Rune() == 'b':
+			this.input.Get().WordLeft()
+			this.Repaint()
+		case ev.Modifiers()&tcell.ModAlt != 0 && ev.Rune() == 'f':
+			this.input.Get().WordRight()
+			this.Repaint()
+		case ev.Modifiers()&tcell.ModAlt != 0 && ev.Rune() == 'c': // case sensitive, smart, insensitive
+			this.caseMode = (this.caseMode + 1) % 3
+			this.status = this.caseMode.String()
+			this.onChange()
+			this.Repaint()
 		default:
 			this.input.Get().Append(string(ev.Rune()))
 			this.onChange()
@@ -72,6 +150,44 @@ func (this *Screen) eventQuery(ev *tcell.EventKey) error {
 	return nil
 }
 
+// incremental search in the history, the current input is the entry found
+func (this *Screen) eventReverseSearch(ev *tcell.EventKey) error {
+	search := func(before int) {
+		i := this.input.Search(this.rsearch.String(), before)
+		if i >= 0 {
+			this.rsPos = i
+			this.input.Get().Set(this.input.History[i].String())
+		} else {
+			this.rsPos = -1
+		}
+		this.onChange()
+	}
+	switch ev.Key() {
+	case tcell.KeyCtrlR: // older
+		if this.rsPos > 0 {
+			search(this.rsPos)
+		}
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		this.rsearch.Backspace()
+		search(this.input.Pos)
+	case tcell.KeyRune:
+		this.rsearch.Append(string(ev.Rune()))
+		search(this.input.Pos)
+	case tcell.KeyEscape, tcell.KeyCtrlG: // cancel
+		this.rsearch = nil
+		this.input.Get().Set(this.rsOrig)
+		this.onChange()
+	case tcell.KeyEnter:
+		this.rsearch = nil
+		this.onEnter()
+	default: // accept, and keep editing
+		this.rsearch = nil
+		return this.eventQuery(ev)
+	}
+	this.Repaint()
+	return nil
+}
+
 func (this *Screen) eventNav(ev *tcell.EventKey) error {
 	this.log("eventNav(%+v)", ev)
 	_, h := this.scr.Size()
@@ -81,14 +197,14 @@ func (this *Screen) eventNav(ev *tcell.EventKey) error {
 		this.Repaint()
 	case tcell.KeyUp:
 		this.detoffset = 0
-		this.buffer.Up(nil)
+		this.buffer.Up(this.visible)
 		if this.row > 0 {
 			this.row--
 		}
 		this.Repaint()
 	case tcell.KeyDown:
 		this.detoffset = 0
-		this.buffer.Down(nil)
+		this.buffer.Down(this.visible)
 		if this.row < h-2 {
 			this.row++
 		}
@@ -101,7 +217,7 @@ func (this *Screen) eventNav(ev *tcell.EventKey) error {
 			}
 		} else {
 			for i := 0; i < 25; i++ {
-				this.buffer.Up(nil)
+				this.buffer.Up(this.visible)
 			}
 			this.row -= 25
 			if this.row < 0 {
@@ -117,7 +233,7 @@ func (this *Screen) eventNav(ev *tcell.EventKey) error {
 			}
 		} else {
 			for i := 0; i < 25; i++ {
-				this.buffer.Down(nil)
+				this.buffer.Down(this.visible)
 			}
 			this.row += 25
 			if this.row > h-2 {
@@ -145,6 +261,10 @@ func (this *Screen) eventNav(ev *tcell.EventKey) error {
 		case '0':
 			this.buffer.Pos = 0
 			this.row = 0
+			this.settle()
+			this.Repaint()
+		case '1', '2', '3', '4': // level threshold
+			this.setThreshold(thresholds[ev.Rune()])
 			this.Repaint()
 		case 'F': // Follow
 			this.buffer.Pos = len(this.buffer.Lines)
@@ -156,132 +276,174 @@ func (this *Screen) eventNav(ev *tcell.EventKey) error {
 		case '/':
 			// search
 			this.query = "SEARCH"
+			this.input = this.searchInput
 			this.input.NewUnlessEmpty()
 			this.onEnter = func() {
 				this.query = ""
+				err := this.input.Commit()
+				if err != nil {
+					this.log("can't save history: %v", err)
+				}
+				if p := this.input.Get().String(); isFuzzy(p) {
+					this.fuzzyMenu(p)
+				}
 			}
 			this.onChange = func() {
 				this.pattern = nil
 				p := this.input.Get().String()
 				this.log("SEARCH: %q", p)
-				if len(p) == 0 {
+				if len(p) == 0 || isFuzzy(p) {
 					return
 				}
-				var err error
-				this.pattern, err = regexp.Compile(p)
-				if err != nil {
-					this.pattern, _ = regexp.Compile(regexp.QuoteMeta(p))
-				}
+				this.pattern = tbuf.ParseQuery(p, this.caseMode)
 			}
 			this.onChange()
 			this.Repaint()
 
 		case 'n': //scan next
-			this.detoffset = 0
-			if this.pattern != nil {
-				this.buffer.Down(func(l tbuf.Line) bool {
-					if this.row < h-2 {
-						this.row++
-					}
-					return l.Mark || this.pattern.MatchString(l.Str)
-				})
-				this.Repaint()
-			} else {
-				this.buffer.Down(func(l tbuf.Line) bool {
-					if this.row < h-2 {
-						this.row++
-					}
-					return l.Mark
-				})
-				this.Repaint()
-			}
+			this.nextMatch(1)
+			this.Repaint()
 		case 'N': //scan prev
-			this.detoffset = 0
-			if this.pattern != nil {
-				this.buffer.Up(func(l tbuf.Line) bool {
-					if this.row > 0 {
-						this.row--
-					}
-					return l.Mark || this.pattern.MatchString(l.Str)
-				})
-				this.Repaint()
-			} else {
-				this.buffer.Up(func(l tbuf.Line) bool {
-					if this.row > 0 {
-						this.row--
-					}
-					return l.Mark
-				})
-				this.Repaint()
-			}
+			this.nextMatch(-1)
+			this.Repaint()
+		case 'e': // next error
+			this.nextLevel("error", 1)
+			this.Repaint()
+		case 'E': // previous error
+			this.nextLevel("error", -1)
+			this.Repaint()
+		case 'w': // next warning
+			this.nextLevel("warn", 1)
+			this.Repaint()
+		case 'W': // previous warning
+			this.nextLevel("warn", -1)
+			this.Repaint()
 
 		case ' ':
 			if _, ok := this.buffer.Get(); ok {
-				this.buffer.Mark()
-				this.buffer.Down(nil)
+				this.markCurrent(this.curSet())
+				this.buffer.Down(this.visible)
 				if this.row < h-2 {
 					this.row++
 				}
 			}
 			this.Repaint()
 
-		case 'M': // unmark all
-			this.buffer.Range(func(i int, l *tbuf.Line) bool {
-				l.Mark = false
-				return true
+		case 'M': // unmark all, in the current set
+			set := this.curSet()
+			this.mark(fmt.Sprintf("unmark all %v", set), func(l *tbuf.Line) {
+				l.Marks &^= set
 			})
 			this.Repaint()
 
 		case 'm': // mark searches
 			if this.pattern != nil {
-				this.buffer.Range(func(i int, l *tbuf.Line) bool {
-					if this.pattern.MatchString(l.Str) {
-						l.Mark = true
+				set := this.curSet()
+				this.mark(fmt.Sprintf("mark /%s/ in %v", this.pattern, set), func(l *tbuf.Line) {
+					if this.visible(*l) && this.pattern.Match(*l) {
+						l.Marks |= set
 					}
-					return true
 				})
 				this.pattern = nil // usually makes sense
 			}
 			this.Repaint()
 
 		case 'g': // grep mode (only show marked)
-			b := &tbuf.Buffer{}
-			this.buffer.Range(func(i int, l *tbuf.Line) bool {
-				if l.Mark {
-					l := *l
-					l.Mark = false
-					b.AppendLine(l)
-				}
-				if i == this.buffer.Pos {
-					b.Pos = len(b.Lines)
-				}
-				return true
-			})
+			set := this.curSet()
+			b := this.buffer.FilterContext(func(l tbuf.Line) bool {
+				return l.Marks&set != 0
+			}, this.context)
 			if len(b.Lines) > 0 {
-				this.buffer = b
+				this.setBuffer(fmt.Sprintf("grep %v", set), b)
 				this.Repaint()
 			}
 		case 'G': // grep mode inverted (only unmarked)
-			b := &tbuf.Buffer{}
-			this.buffer.Range(func(i int, l *tbuf.Line) bool {
-				if !l.Mark {
-					l := *l
-					l.Mark = false
-					b.AppendLine(l)
-				}
-				if i == this.buffer.Pos {
-					b.Pos = len(b.Lines)
-				}
-				return true
+			set := this.curSet()
+			b := this.buffer.Filter(func(l tbuf.Line) bool {
+				return l.Marks&set == 0
 			})
 			if len(b.Lines) > 0 {
-				this.buffer = b
+				this.setBuffer(fmt.Sprintf("grep not %v", set), b)
 				this.Repaint()
 			}
+		case 'f': // failures (only errors and above)
+			min := tbuf.Severity("error")
+			if min < 0 {
+				this.status = `no "error" level in -level-order`
+				this.Repaint()
+				break
+			}
+			b := this.buffer.FilterContext(func(l tbuf.Line) bool {
+				return l.Severity() >= min
+			}, this.context)
+			if len(b.Lines) > 0 {
+				this.setBuffer("grep failures", b)
+				this.Repaint()
+			}
+
+		case 't': // jump to a time, or a window A..B
+			this.prompt("time: ", this.timeInput, this.gotoTime)
+			this.Repaint()
+		case 'T': // time step
+			this.prompt(fmt.Sprintf("step (%v): ", TimeStep), this.stepInput, func(s string) {
+				d, err := time.ParseDuration(s)
+				if err != nil || d <= 0 {
+					this.status = fmt.Sprintf("bad step %q", s)
+					return
+				}
+				TimeStep = d
+				this.status = fmt.Sprintf("step %v", d)
+			})
+			this.Repaint()
+		case '[':
+			this.stepTime(-1)
+			this.Repaint()
+		case ']':
+			this.stepTime(1)
+			this.Repaint()
+
+		case 'p': // pin the search
+			this.pinSearch()
+			this.Repaint()
+		case 'P': // list the pins
+			this.pinMenu()
+			this.Repaint()
+		case '&': // exclude
+			this.prompt("exclude: ", this.exclInput, this.addExclude)
+			this.Repaint()
+		case 'X': // list the exclusions
+			this.excludeMenu()
+			this.Repaint()
+
+		case 's': // pick the mark set
+			this.setMenu()
+			this.Repaint()
+		case 'S': // combine the mark sets
+			this.prompt("sets (a & !b, c = a | b): ", this.setInput, this.markExpr)
+			this.Repaint()
+
+		case 'u': // undo
+			this.undo()
+			this.Repaint()
+		case 'U': // redo
+			this.redo()
+			this.Repaint()
+
+		case '+': // more context
+			this.context++
+			this.status = fmt.Sprintf("%d lines of context", this.context)
+			this.Repaint()
+		case '-': // less context
+			if this.context > 0 {
+				this.context--
+			}
+			this.status = fmt.Sprintf("%d lines of context", this.context)
+			this.Repaint()
 
 		case 'O':
-			this.detoffset = 0
-			this.buffer = this.origBuf
+			if this.buffer != this.origBuf {
+				this.setBuffer("original buffer", this.origBuf)
+			}
 			this.Repaint()
 
 		//case '?': // search backward ?
@@ -292,11 +454,20 @@ func (this *Screen) eventNav(ev *tcell.EventKey) error {
 				this.details = 0
 			}
 			this.Repaint()
+		case 'a': // alphabetical order of the tags
+			this.alpha = !this.alpha
+			this.Repaint()
+		case 'R': // render ansi colors
+			this.ANSI = !this.ANSI
+			this.Repaint()
+		case '_': // journal trusted fields
+			this.trusted = !this.trusted
+			this.Repaint()
 		case 'q':
 			return Exit
 
 		case 'c': // copy line
-			if line, ok := this.buffer.Get(); ok {
+			if line, ok := this.buffer.Get(); ok && !line.Divider {
 				this.log("copying %s", line.Str)
 				err := util.ClipCopy(line.Str)
 				if err != nil {
@@ -305,8 +476,9 @@ func (this *Screen) eventNav(ev *tcell.EventKey) error {
 			}
 		case 'C': // copy marked
 			out := []string{}
+			set := this.curSet()
 			this.buffer.Range(func(i int, l *tbuf.Line) bool {
-				if l.Mark {
+				if l.Marks&set != 0 && !l.Divider {
 					out = append(out, l.Str+"\n")
 				}
 				return true
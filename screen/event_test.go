@@ -0,0 +1,28 @@
+package screen
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/ohait/jl/tbuf"
+	"github.com/ohait/jl/util"
+)
+
+func TestPasteError(t *testing.T) {
+	if _, err := exec.LookPath("xclip"); err == nil {
+		t.Skip("xclip is installed, pasting can't fail")
+	}
+	s := simScreen(t, 60, 10)
+	s.log = t.Log
+	s.buffer = &tbuf.Buffer{}
+	s.origBuf = s.buffer
+	s.prompt("time: ", &util.HistoryInput{}, func(string) {})
+	if err := s.event(tcell.NewEventKey(tcell.KeyCtrlV, 0, 0)); err != nil {
+		t.Fatalf("expected the error in the status, got %v", err)
+	}
+	if s.query != "time: " || !strings.HasPrefix(s.status, "paste error:") {
+		t.Fatalf("expected to stay in the prompt, got %q %q", s.query, s.status)
+	}
+}
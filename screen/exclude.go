@@ -0,0 +1,82 @@
+package screen
+
+import (
+	"fmt"
+
+	"github.com/ohait/jl/tbuf"
+)
+
+// an exclude pattern, the matching lines are hidden
+type exclusion struct {
+	q   *tbuf.Query
+	off bool
+}
+
+func (this *Screen) addExclude(p string) {
+	if p == "" {
+		return
+	}
+	this.changeFilters(fmt.Sprintf("exclude /%s/", p), func() {
+		this.excludes = append(this.excludes, &exclusion{q: tbuf.ParseQuery(p, this.caseMode)})
+	})
+	this.status = fmt.Sprintf("excluding /%s/", p)
+}
+
+// restart counting the hidden lines if the buffer or the filters changed,
+// or continue over the new lines
+func (this *Screen) syncHidden() {
+	s := this.hiddenSearch
+	if s != nil && (s.buffer != this.buffer || s.filter.gen != this.filter.gen) {
+		s.Cancel()
+		this.hiddenSearch, s = nil, nil
+	}
+	if !this.filter.leveled && len(this.filter.excludes) == 0 {
+		return
+	}
+	if s == nil {
+		s = newHiddenSearch(this.buffer, this.filter, this.refreshLater, this.log)
+		this.hiddenSearch = s
+	}
+	s.start()
+}
+
+// how many lines of the buffer are not visible, counted so far
+func (this *Screen) hidden() (int, bool) {
+	if this.hiddenSearch == nil {
+		return 0, true
+	}
+	return this.hiddenSearch.Count()
+}
+
+// list the exclusions, to toggle or remove them
+func (this *Screen) excludeMenu() {
+	this.openMenu(&menu{
+		title: "exclusions",
+		help:  "enter: toggle, ",
+		items: func() (out []string) {
+			for _, x := range this.excludes {
+				on := "x"
+				if x.off {
+					on = " "
+				}
+				out = append(out, fmt.Sprintf("[%s] %s", on, x.q))
+			}
+			return
+		},
+		onEnter: func(i int) {
+			x := this.excludes[i]
+			verb := "disable"
+			if x.off {
+				verb = "enable"
+			}
+			this.changeFilters(fmt.Sprintf("%s exclude /%s/", verb, x.q), func() {
+				x.off = !x.off
+			})
+		},
+		onDelete: func(i int) {
+			this.changeFilters(fmt.Sprintf("remove exclude /%s/", this.excludes[i].q), func() {
+				this.excludes = append(this.excludes[:i:i], this.excludes[i+1:]...)
+			})
+		},
+	})
+}
@@ -0,0 +1,93 @@
+package screen
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/ohait/jl/tbuf"
+)
+
+// the hidden lines, once all counted
+func waitHidden(t *testing.T, s *Screen) int {
+	s.syncHidden()
+	if s.hiddenSearch == nil {
+		return 0
+	}
+	return waitSearch(t, s.hiddenSearch)
+}
+
+func TestExclude(t *testing.T) {
+	s := simScreen(t, 60, 20)
+	s.buffer = &tbuf.Buffer{}
+	for _, l := range []string{"GET /health", "GET /api", "GET /health", "POST /api"} {
+		s.buffer.Append(l, t.Log)
+	}
+	s.buffer.Pos = 0
+
+	s.addExclude("/health")
+	if n := waitHidden(t, s); n != 2 {
+		t.Fatalf("expected 2 hidden, got %d", n)
+	}
+	if s.buffer.Pos != 1 {
+		t.Fatalf("expected to move off the hidden line, got %d", s.buffer.Pos)
+	}
+	s.buffer.Down(s.visible)
+	if s.buffer.Pos != 3 {
+		t.Fatalf("expected to skip the hidden line, got %d", s.buffer.Pos)
+	}
+
+	s.buffer.Append("GET /health", t.Log)
+	if n := waitHidden(t, s); n != 3 {
+		t.Fatalf("expected the new line to be hidden, got %d", n)
+	}
+
+	s.excludeMenu()
+	if items := s.menu.items(); len(items) != 1 || items[0] != "[x] /health" {
+		t.Fatalf("bad items: %q", items)
+	}
+	s.eventMenu(tcell.NewEventKey(tcell.KeyEnter, 0, 0))
+	if n := waitHidden(t, s); n != 0 {
+		t.Fatalf("expected nothing hidden when off, got %d", n)
+	}
+	s.eventMenu(tcell.NewEventKey(tcell.KeyRune, 'x', 0))
+	if len(s.excludes) != 0 {
+		t.Fatalf("expected the exclusion removed")
+	}
+	s.eventMenu(tcell.NewEventKey(tcell.KeyEscape, 0, 0))
+	if s.menu != nil {
+		t.Fatalf("expected the menu closed")
+	}
+
+	s.addExclude("m:^(GET|POST) /api$")
+	if n := waitHidden(t, s); n != 2 {
+		t.Fatalf("expected a query to hide 2 lines, got %d", n)
+	}
+}
+
+// the hidden lines are recounted by the repaints of the main loop, while the filters change
+func TestExcludeTick(t *testing.T) {
+	s := simScreen(t, 60, 20)
+	s.log = t.Log
+	s.buffer = &tbuf.Buffer{}
+	s.origBuf = s.buffer
+	for i := 0; i < 1000; i++ {
+		s.buffer.Append("GET /health", t.Log)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			s.Tick(true)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		s.m.Lock()
+		s.addExclude("health")
+		s.undo()
+		s.m.Unlock()
+	}
+	<-done
+	if n := waitHidden(t, s); n != 0 {
+		t.Fatalf("expected nothing hidden, got %d", n)
+	}
+}
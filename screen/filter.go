@@ -0,0 +1,82 @@
+package screen
+
+import (
+	"fmt"
+
+	"github.com/ohait/jl/tbuf"
+)
+
+// the keys for the level threshold
+var thresholds = map[rune]string{
+	'1': "debug",
+	'2': "info",
+	'3': "warn",
+	'4': "error",
+}
+
+// what is hidden, rebuilt when it changes so a copy can be used by other goroutines
+type filter struct {
+	leveled  bool // hide the lines less severe than min, and unknown levels
+	min      int
+	excludes []*tbuf.Query
+	gen      int // changes every time the filters do
+}
+
+func (this filter) visible(l tbuf.Line) bool {
+	if l.Divider { // not a real line, it has no level to filter by
+		return true
+	}
+	if this.leveled && l.Severity() < this.min {
+		return false
+	}
+	for _, q := range this.excludes {
+		if q.Match(l) {
+			return false
+		}
+	}
+	return true
+}
+
+// tells if a line is shown, or hidden by the filters
+func (this *Screen) visible(l tbuf.Line) bool {
+	return this.filter.visible(l)
+}
+
+// set the level threshold, or clear it if already set
+func (this *Screen) setThreshold(level string) {
+	if this.threshold == level {
+		level = ""
+		this.status = "all levels"
+	} else if tbuf.Severity(level) < 0 {
+		this.status = fmt.Sprintf("no %q level in -level-order", level)
+		return
+	} else {
+		this.status = fmt.Sprintf("only %s and above", level)
+	}
+	this.changeFilters(this.status, func() {
+		this.threshold = level
+	})
+}
+
+// the filters changed: rebuild them, recount the hidden lines and move off a hidden one
+func (this *Screen) filtersChanged() {
+	f := filter{gen: this.filter.gen + 1}
+	if this.threshold != "" {
+		f.leveled, f.min = true, tbuf.Severity(this.threshold)
+	}
+	for _, x := range this.excludes {
+		if !x.off {
+			f.excludes = append(f.excludes, x.q)
+		}
+	}
+	this.filter = f
+	this.syncHidden()
+	this.settle()
+}
+
+// if the current line is hidden, move to the next visible one
+func (this *Screen) settle() {
+	if l, ok := this.buffer.Get(); ok && !this.visible(l) {
+		this.buffer.Down(this.visible)
+	}
+}
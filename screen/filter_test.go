@@ -0,0 +1,90 @@
+package screen
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/ohait/jl/tbuf"
+)
+
+func TestThreshold(t *testing.T) {
+	s := simScreen(t, 40, 10)
+	s.buffer = &tbuf.Buffer{}
+	for _, l := range []string{"debug", "info", "warn", "error", "debug"} {
+		s.buffer.Append(`{"level":"`+l+`","message":"x"}`, t.Log)
+	}
+	s.buffer.Append("no level", t.Log)
+	s.buffer.Pos = 0
+
+	s.setThreshold("warn")
+	if s.buffer.Pos != 2 {
+		t.Fatalf("expected to move to the first warning, got %d", s.buffer.Pos)
+	}
+	s.buffer.Down(s.visible)
+	s.buffer.Down(s.visible)
+	if s.buffer.Pos != 6 {
+		t.Fatalf("expected the tail, got %d", s.buffer.Pos)
+	}
+	s.buffer.Up(s.visible)
+	if s.buffer.Pos != 3 {
+		t.Fatalf("expected the error, got %d", s.buffer.Pos)
+	}
+
+	s.setThreshold("warn")
+	if s.threshold != "" || !s.visible(s.buffer.At(5)) {
+		t.Fatalf("expected the threshold to be cleared")
+	}
+}
+
+func TestDividers(t *testing.T) {
+	s := simScreen(t, 40, 10)
+	b := &tbuf.Buffer{}
+	for _, l := range []string{"a-1", "b", "c", "d", "e", "a-2"} {
+		b.Append(`{"level":"info","message":"`+l+`"}`, t.Log)
+	}
+	s.buffer = b.FilterContext(func(l tbuf.Line) bool { return l.Short[0] == 'a' }, 1)
+	if s.buffer.Size() != 5 || !s.buffer.At(2).Divider {
+		t.Fatalf("expected a divider")
+	}
+
+	s.markExpr("b = !a")
+	if s.buffer.At(2).Marks != 0 || s.buffer.At(3).Marks == 0 {
+		t.Fatalf("expected the divider not marked")
+	}
+	s.buffer.Pos = 2
+	s.markCurrent(1)
+	if s.buffer.At(2).Marks != 0 || s.ops.last() != "b = !a (4 lines)" {
+		t.Fatalf("expected nothing to mark, got %q", s.ops.last())
+	}
+
+	s.setThreshold("warn")
+	if n := waitHidden(t, s); n != 4 || !s.visible(s.buffer.At(2)) {
+		t.Fatalf("expected only the real lines hidden, got %d", n)
+	}
+}
+
+func TestFailuresNoLevel(t *testing.T) {
+	order, aliases := tbuf.LevelOrder, map[string]string{}
+	for k, v := range tbuf.LevelAliases {
+		aliases[k] = v
+	}
+	defer func() { tbuf.LevelOrder, tbuf.LevelAliases = order, aliases }()
+	tbuf.SetLevelOrder([]string{"debug", "info", "warn", "critical"})
+
+	s := simScreen(t, 40, 10)
+	s.log = t.Log
+	s.buffer = &tbuf.Buffer{}
+	s.origBuf = s.buffer
+	s.buffer.Append(`{"level":"info","message":"x"}`, t.Log)
+	if err := s.event(tcell.NewEventKey(tcell.KeyRune, 'f', 0)); err != nil {
+		t.Fatal(err)
+	}
+	if s.buffer != s.origBuf || s.status != `no "error" level in -level-order` {
+		t.Fatalf("expected an error, got %q", s.status)
+	}
+
+	s.setThreshold("error")
+	if s.threshold != "" || !s.visible(s.buffer.At(0)) || s.status != `no "error" level in -level-order` {
+		t.Fatalf("expected no threshold, got %q", s.status)
+	}
+}
@@ -0,0 +1,183 @@
+package screen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ohait/jl/tbuf"
+	"github.com/ohait/jl/util"
+)
+
+// how many fuzzy hits are shown
+const fuzzyShow = 15
+
+// how much of a line is scored, the rest is ignored
+const fuzzyMaxText = 2000
+
+type fuzzyHit struct {
+	pos   int
+	score int
+	size  int // of the text matched, shorter is better for the same score
+}
+
+// better tells if h ranks before o: the best first, then the shortest, then the earliest
+func (h fuzzyHit) better(o fuzzyHit) bool {
+	if h.score != o.score {
+		return h.score > o.score
+	}
+	if h.size != o.size {
+		return h.size < o.size
+	}
+	return h.pos < o.pos
+}
+
+// the best lines by fuzzy score on the message, and on the values too if
+// values is set, found in the background
+type fuzzySearch struct {
+	buffer  *tbuf.Buffer
+	pattern string
+	values  bool
+	visible func(tbuf.Line) bool
+	onMore  func() // called from the search goroutine when a chunk is done
+	log     func(...interface{})
+	stop    chan struct{}
+	once    sync.Once // to close stop
+
+	m    sync.Mutex
+	hits []fuzzyHit // the best ones, at most fuzzyShow
+	done bool
+}
+
+func newFuzzySearch(b *tbuf.Buffer, pattern string, values bool, visible func(tbuf.Line) bool, onMore func(), log func(...interface{})) *fuzzySearch {
+	s := &fuzzySearch{
+		buffer:  b,
+		pattern: pattern,
+		values:  values,
+		visible: visible,
+		onMore:  onMore,
+		log:     log,
+		stop:    make(chan struct{}),
+	}
+	go util.Recover(s.scan, func(r interface{}, stack string) {
+		s.log("fuzzy search panic: %v\n%s", r, stack)
+	})
+	return s
+}
+
+func (this *fuzzySearch) scan() {
+	for from := 0; ; from += searchChunk {
+		select {
+		case <-this.stop:
+			return
+		default:
+		}
+		lines := this.buffer.Slice(from, from+searchChunk)
+		var found []fuzzyHit
+		for i, l := range lines {
+			if l.Divider || !this.visible(l) {
+				continue
+			}
+			text := this.text(l)
+			size := len(text)
+			if len(text) > fuzzyMaxText {
+				text = text[:fuzzyMaxText]
+			}
+			if sc, ok := util.FuzzyScore(this.pattern, text); ok {
+				found = append(found, fuzzyHit{pos: from + i, score: sc, size: size})
+			}
+		}
+		this.m.Lock()
+		for _, h := range found {
+			this.add(h)
+		}
+		this.m.Unlock()
+		if len(lines) == 0 {
+			this.m.Lock()
+			this.done = true
+			this.m.Unlock()
+		}
+		if this.onMore != nil {
+			this.onMore()
+		}
+		if len(lines) == 0 {
+			return
+		}
+	}
+}
+
+// what is scored of a line
+func (this *fuzzySearch) text(l tbuf.Line) string {
+	if !this.values {
+		return l.Message()
+	}
+	parts := []string{l.Message()}
+	for _, k := range l.OrderedTags() {
+		parts = append(parts, l.Value(k))
+	}
+	return strings.Join(parts, " ")
+}
+
+// keep h if among the best, with the lock held
+func (this *fuzzySearch) add(h fuzzyHit) {
+	i := sort.Search(len(this.hits), func(j int) bool { return h.better(this.hits[j]) })
+	if i >= fuzzyShow {
+		return
+	}
+	this.hits = append(this.hits, fuzzyHit{})
+	copy(this.hits[i+1:], this.hits[i:])
+	this.hits[i] = h
+	if len(this.hits) > fuzzyShow {
+		this.hits = this.hits[:fuzzyShow]
+	}
+}
+
+// Hits returns the best hits so far, and if the whole buffer has been scanned
+func (this *fuzzySearch) Hits() ([]fuzzyHit, bool) {
+	this.m.Lock()
+	defer this.m.Unlock()
+	return append([]fuzzyHit(nil), this.hits...), this.done
+}
+
+// cancel the scan, it can be called more than once
+func (this *fuzzySearch) Cancel() {
+	this.once.Do(func() { close(this.stop) })
+}
+
+// ~pattern searches the messages, ~~pattern also the values
+func isFuzzy(p string) bool {
+	return strings.HasPrefix(p, "~")
+}
+
+// show the best fuzzy hits, updated while searching; enter jumps to one
+func (this *Screen) fuzzyMenu(p string) {
+	values := strings.HasPrefix(p, "~~")
+	b := this.buffer
+	s := newFuzzySearch(b, strings.TrimLeft(p, "~"), values, this.filter.visible, this.refreshLater, this.log)
+	this.openMenu(&menu{
+		title: p,
+		help:  "enter: jump, ",
+		items: func() (out []string) {
+			hits, done := s.Hits()
+			for _, h := range hits {
+				l := b.At(h.pos)
+				out = append(out, fmt.Sprintf("%-5s %s", l.Level, l.Message()))
+			}
+			if !done {
+				out = append(out, "searching...")
+			}
+			return
+		},
+		onEnter: func(i int) {
+			hits, _ := s.Hits()
+			if i >= len(hits) {
+				return
+			}
+			this.closeMenu()
+			this.jump(hits[i].pos)
+			this.status = fmt.Sprintf("fuzzy hit %d of %d", i+1, len(hits))
+		},
+		onClose: s.Cancel,
+	})
+}
@@ -0,0 +1,82 @@
+package screen
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ohait/jl/tbuf"
+)
+
+func waitFuzzy(t *testing.T, s *fuzzySearch) []fuzzyHit {
+	for i := 0; i < 500; i++ {
+		if hits, done := s.Hits(); done {
+			return hits
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("fuzzy search not done")
+	return nil
+}
+
+func TestFuzzyHits(t *testing.T) {
+	b := &tbuf.Buffer{}
+	for _, l := range []string{
+		`{"message":"request done","error":"conn refused, upstream"}`,
+		`{"message":"connection refused by upstream"}`,
+		`{"message":"all good"}`,
+		`{"message":"conn refused upstream"}`,
+	} {
+		b.Append(l, t.Log)
+	}
+	all := func(tbuf.Line) bool { return true }
+
+	hits := waitFuzzy(t, newFuzzySearch(b, "conn refsd upstrm", false, all, nil, t.Log))
+	if len(hits) != 2 || hits[0].pos != 3 || hits[1].pos != 1 {
+		t.Fatalf("bad hits: %+v", hits)
+	}
+	hits = waitFuzzy(t, newFuzzySearch(b, "conn refsd upstrm", true, all, nil, t.Log))
+	if len(hits) != 3 {
+		t.Fatalf("expected the values to match too: %+v", hits)
+	}
+	hits = waitFuzzy(t, newFuzzySearch(b, "conn refsd upstrm", false, func(l tbuf.Line) bool { return l.Short != "conn refused upstream" }, nil, t.Log))
+	if len(hits) != 1 || hits[0].pos != 1 {
+		t.Fatalf("expected hidden lines skipped: %+v", hits)
+	}
+
+	s := simScreen(t, 60, 20)
+	s.buffer = b
+	s.fuzzyMenu("~conn refsd upstrm")
+	if s.menu == nil {
+		t.Fatalf("expected a menu")
+	}
+	for i := 0; i < 500 && len(s.menu.items()) != 2; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if items := s.menu.items(); len(items) != 2 {
+		t.Fatalf("expected a menu with 2 hits, got %q", items)
+	}
+	s.menu.onEnter(1)
+	if s.menu != nil || s.buffer.Pos != 1 {
+		t.Fatalf("expected to jump to the second hit, got %d", s.buffer.Pos)
+	}
+}
+
+func TestFuzzyBest(t *testing.T) {
+	b := &tbuf.Buffer{}
+	for i := 0; i < 3*searchChunk; i++ {
+		b.Append(fmt.Sprintf(`{"message":"request %d done"}`, i), t.Log)
+	}
+	hits := waitFuzzy(t, newFuzzySearch(b, "req 1 dn", false, func(tbuf.Line) bool { return true }, nil, t.Log))
+	if len(hits) != fuzzyShow {
+		t.Fatalf("expected %d hits, got %d", fuzzyShow, len(hits))
+	}
+	for i := 1; i < len(hits); i++ {
+		if hits[i].better(hits[i-1]) {
+			t.Fatalf("not sorted: %+v", hits)
+		}
+	}
+	if hits[0].pos != 1 {
+		t.Errorf("expected the shortest first, got %+v", hits[0])
+	}
+}
@@ -0,0 +1,60 @@
+package screen
+
+import (
+	"fmt"
+
+	"github.com/ohait/jl/tbuf"
+)
+
+// jump to the next (dir > 0) or previous line at or above the level, wrapping at the ends
+func (this *Screen) nextLevel(level string, dir int) {
+	min := tbuf.Severity(level)
+	if min < 0 {
+		this.status = fmt.Sprintf("unknown level %q", level)
+		return
+	}
+	match := func(l tbuf.Line) bool {
+		return l.Severity() >= min && this.visible(l)
+	}
+	pos := this.buffer.Pos
+	wrapped := false
+	var ok bool
+	if dir > 0 {
+		_, ok = this.buffer.Down(match)
+		if !ok {
+			this.buffer.Pos = -1
+			_, ok = this.buffer.Down(match)
+			wrapped = true
+		}
+	} else {
+		_, ok = this.buffer.Up(match)
+		if !ok {
+			this.buffer.Pos = this.buffer.Size()
+			_, ok = this.buffer.Up(match)
+			wrapped = true
+		}
+	}
+	if !ok {
+		this.buffer.Pos = pos
+		this.status = fmt.Sprintf("no %s lines", level)
+		return
+	}
+	found := this.buffer.Pos
+	this.buffer.Pos = pos
+	this.jump(found)
+
+	n, tot := 0, 0
+	this.buffer.Range(func(i int, l *tbuf.Line) bool {
+		if match(*l) {
+			tot++
+			if i <= found {
+				n++
+			}
+		}
+		return true
+	})
+	this.status = fmt.Sprintf("%s %d of %d", level, n, tot)
+	if wrapped {
+		this.status += " (wrapped)"
+	}
+}
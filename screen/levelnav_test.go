@@ -0,0 +1,68 @@
+package screen
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/ohait/jl/tbuf"
+)
+
+func TestNextLevel(t *testing.T) {
+	s := simScreen(t, 40, 10)
+	s.buffer = &tbuf.Buffer{}
+	for _, l := range []string{"info", "error", "debug", "warn", "fatal", "info"} {
+		s.buffer.Append(`{"level":"`+l+`","message":"x"}`, t.Log)
+	}
+	s.buffer.Pos = 0
+
+	s.nextLevel("error", 1)
+	if s.buffer.Pos != 1 || s.status != "error 1 of 2" {
+		t.Fatalf("got %d %q", s.buffer.Pos, s.status)
+	}
+	s.nextLevel("error", 1)
+	if s.buffer.Pos != 4 || s.status != "error 2 of 2" {
+		t.Fatalf("got %d %q", s.buffer.Pos, s.status)
+	}
+	s.nextLevel("error", 1)
+	if s.buffer.Pos != 1 || s.status != "error 1 of 2 (wrapped)" {
+		t.Fatalf("got %d %q", s.buffer.Pos, s.status)
+	}
+	s.nextLevel("error", -1)
+	if s.buffer.Pos != 4 || s.status != "error 2 of 2 (wrapped)" {
+		t.Fatalf("got %d %q", s.buffer.Pos, s.status)
+	}
+	s.nextLevel("warn", -1)
+	if s.buffer.Pos != 3 || s.status != "warn 2 of 3" {
+		t.Fatalf("got %d %q", s.buffer.Pos, s.status)
+	}
+
+	s.buffer = &tbuf.Buffer{}
+	s.buffer.Append(`{"level":"info","message":"x"}`, t.Log)
+	s.buffer.Pos = 0
+	s.nextLevel("error", 1)
+	if s.buffer.Pos != 0 || s.status != "no error lines" {
+		t.Fatalf("got %d %q", s.buffer.Pos, s.status)
+	}
+}
+
+func TestLevelKeys(t *testing.T) {
+	s := simScreen(t, 40, 10)
+	s.log = t.Log
+	s.buffer = &tbuf.Buffer{}
+	s.origBuf = s.buffer
+	for _, l := range []string{"info", "warn", "error", "info"} {
+		s.buffer.Append(`{"level":"`+l+`","message":"x"}`, t.Log)
+	}
+	s.buffer.Pos = 0
+	for _, c := range []struct {
+		key rune
+		pos int
+	}{{'e', 2}, {'W', 1}, {'w', 2}, {'E', 2}} {
+		if err := s.event(tcell.NewEventKey(tcell.KeyRune, c.key, 0)); err != nil {
+			t.Fatal(err)
+		}
+		if s.buffer.Pos != c.pos {
+			t.Fatalf("%c: expected %d, got %d", c.key, c.pos, s.buffer.Pos)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package screen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell"
+	"github.com/ohait/jl/tbuf"
+)
+
+// the background of the lines in each mark set, a to h
+var markColors = []tcell.Color{
+	tcell.Color52,
+	tcell.Color22,
+	tcell.Color18,
+	tcell.Color58,
+	tcell.Color54,
+	tcell.Color23,
+	tcell.Color94,
+	tcell.Color238,
+}
+
+// the background for the first set the line is in
+func markColor(m tbuf.Marks) tcell.Color {
+	for i := range markColors {
+		if m&(1<<uint(i)) != 0 {
+			return markColors[i]
+		}
+	}
+	return tcell.ColorDefault
+}
+
+// the set used by marking, a by default
+func (this *Screen) curSet() tbuf.Marks {
+	if this.set == 0 {
+		return 1
+	}
+	return this.set
+}
+
+// how many lines of the buffer are in each set
+func (this *Screen) markCounts() []int {
+	out := make([]int, len(tbuf.MarkSets))
+	this.buffer.Range(func(i int, l *tbuf.Line) bool {
+		for j := range out {
+			if l.Marks&(1<<uint(j)) != 0 {
+				out[j]++
+			}
+		}
+		return true
+	})
+	return out
+}
+
+// pick the set used by marking
+func (this *Screen) setMenu() {
+	counts := this.markCounts()
+	m := &menu{
+		title: "mark sets",
+		help:  "enter: use, ",
+		items: func() (out []string) {
+			for i, c := range counts {
+				cur := " "
+				if this.curSet() == 1<<uint(i) {
+					cur = "*"
+				}
+				out = append(out, fmt.Sprintf("%s %c: %d lines", cur, tbuf.MarkSets[i], c))
+			}
+			return
+		},
+		onEnter: func(i int) {
+			this.set = 1 << uint(i)
+			this.closeMenu()
+			this.status = fmt.Sprintf("marking in %v", this.set)
+		},
+	}
+	for i := range counts {
+		if this.curSet() == 1<<uint(i) {
+			m.sel = i
+		}
+	}
+	this.openMenu(m)
+}
+
+// "a & !b" makes a new buffer, "c = a | b" fills the set c
+func (this *Screen) markExpr(s string) {
+	if strings.TrimSpace(s) == "" {
+		return
+	}
+	e, err := tbuf.ParseMarkExpr(s)
+	if err != nil {
+		this.status = err.Error()
+		return
+	}
+	if e.Assign != 0 {
+		n := 0
+		this.mark(e.String(), func(l *tbuf.Line) {
+			if e.Match(*l) {
+				l.Marks |= e.Assign
+				n++
+			} else {
+				l.Marks &^= e.Assign
+			}
+		})
+		this.status = fmt.Sprintf("%v: %d lines", e.Assign, n)
+		return
+	}
+	b := this.buffer.FilterContext(e.Match, this.context)
+	if len(b.Lines) == 0 {
+		this.status = fmt.Sprintf("no lines in %s", e)
+		return
+	}
+	this.setBuffer(e.String(), b)
+	this.status = e.String()
+}
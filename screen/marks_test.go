@@ -0,0 +1,49 @@
+package screen
+
+import (
+	"testing"
+
+	"github.com/ohait/jl/tbuf"
+)
+
+func TestMarkSets(t *testing.T) {
+	s := simScreen(t, 60, 20)
+	s.buffer = &tbuf.Buffer{}
+	for _, l := range []string{"user=42 GET /health", "user=42 GET /api", "user=7 GET /health", "user=42 POST /api"} {
+		s.buffer.Append(l, t.Log)
+	}
+	mark := func(set byte, q string) {
+		s.set, _ = tbuf.MarkSet(set)
+		p := tbuf.ParseQuery(q, tbuf.CaseSensitive)
+		s.buffer.Range(func(i int, l *tbuf.Line) bool {
+			if p.Match(*l) {
+				l.Marks |= s.curSet()
+			}
+			return true
+		})
+	}
+	mark('a', "user=42")
+	mark('b', "/health")
+
+	s.markExpr("c = a & !b")
+	if s.status != "c: 2 lines" {
+		t.Fatalf("bad status %q", s.status)
+	}
+	s.markExpr("a - b")
+	if len(s.buffer.Lines) != 2 || s.buffer.Lines[0].Str != "user=42 GET /api" || s.buffer.Lines[0].Marks != 0 {
+		t.Fatalf("bad buffer: %+v", s.buffer.Lines)
+	}
+	s.markExpr("a &")
+	if s.status != "expected a set at the end" || len(s.buffer.Lines) != 2 {
+		t.Fatalf("expected an error, got %q", s.status)
+	}
+
+	s.setMenu()
+	if items := s.menu.items(); items[1] != "* b: 0 lines" {
+		t.Fatalf("bad items: %q", items)
+	}
+	s.menu.onEnter(2)
+	if s.curSet() != 4 || s.menu != nil {
+		t.Fatalf("expected set c, got %v", s.curSet())
+	}
+}
@@ -0,0 +1,107 @@
+package screen
+
+import (
+	"github.com/gdamore/tcell"
+	"github.com/mattn/go-runewidth"
+)
+
+// a list shown over the lines, to pick or remove an entry
+type menu struct {
+	title    string
+	items    func() []string // called on every paint, the list can change
+	help     string          // shown below the items
+	sel      int
+	onEnter  func(i int) // keeps the menu open, close it with esc
+	onDelete func(i int)
+	onClose  func() // when closed, or replaced by another menu
+}
+
+func (this *Screen) openMenu(m *menu) {
+	this.closeMenu()
+	this.menu = m
+	this.query = ""
+}
+
+func (this *Screen) closeMenu() {
+	if this.menu != nil && this.menu.onClose != nil {
+		this.menu.onClose()
+	}
+	this.menu = nil
+}
+
+func (this *Screen) eventMenu(ev *tcell.EventKey) error {
+	m := this.menu
+	n := len(m.items())
+	switch ev.Key() {
+	case tcell.KeyEscape, tcell.KeyCtrlG:
+		this.closeMenu()
+	case tcell.KeyUp:
+		if m.sel > 0 {
+			m.sel--
+		}
+	case tcell.KeyDown:
+		if m.sel < n-1 {
+			m.sel++
+		}
+	case tcell.KeyEnter:
+		if m.sel < n && m.onEnter != nil {
+			m.onEnter(m.sel)
+		}
+	case tcell.KeyDelete, tcell.KeyBackspace, tcell.KeyBackspace2:
+		this.menuDelete()
+	case tcell.KeyRune:
+		switch r := ev.Rune(); {
+		case r >= '1' && r <= '9':
+			if i := int(r - '1'); i < n {
+				m.sel = i
+			}
+		case r == 'x' || r == 'd':
+			this.menuDelete()
+		case r == 'q':
+			this.closeMenu()
+		}
+	}
+	this.Refresh = true
+	return nil
+}
+
+func (this *Screen) menuDelete() {
+	m := this.menu
+	if m.onDelete == nil || m.sel >= len(m.items()) {
+		return
+	}
+	m.onDelete(m.sel)
+	if n := len(m.items()); m.sel >= n && m.sel > 0 {
+		m.sel = n - 1
+	}
+}
+
+func (this *Screen) paintMenu() {
+	if this.menu == nil {
+		return
+	}
+	m := this.menu
+	st := tcell.StyleDefault.Background(tcell.Color237).Foreground(tcell.Color222)
+	cur := this.NewCursor(20, 2)
+	cur.Style = st.Bold(true)
+	cur = cur.Printf(" %-40s ", m.title).CR(20)
+	cur.Style = st
+	items := m.items()
+	if len(items) == 0 {
+		cur = cur.Printf(" %-40s ", "(empty)").CR(20)
+	}
+	for i, s := range items {
+		cur.Style = st.Reverse(i == m.sel)
+		key := ' '
+		if i < 9 {
+			key = rune('1' + i)
+		}
+		cur = cur.Printf(" %c %s ", key, runewidth.FillRight(runewidth.Truncate(s, 38, "…"), 38)).CR(20)
+	}
+	cur.Style = st.Dim(true)
+	help := m.help
+	if m.onDelete != nil {
+		help += "x: remove, "
+	}
+	cur.Printf(" %-40s ", help+"esc: close")
+}
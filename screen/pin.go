@@ -0,0 +1,92 @@
+package screen
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell"
+	"github.com/mattn/go-runewidth"
+	"github.com/ohait/jl/tbuf"
+)
+
+// the colors of the pinned highlights, reused when there are more
+var pinColors = []tcell.Color{
+	tcell.Color214,
+	tcell.Color141,
+	tcell.Color120,
+	tcell.Color211,
+	tcell.Color75,
+	tcell.Color227,
+}
+
+// a pattern which stays highlighted
+type pin struct {
+	q     *tbuf.Query
+	color tcell.Color
+}
+
+// pin the current search, with the next free color
+func (this *Screen) pinSearch() {
+	if this.pattern == nil {
+		this.status = "search something to pin it"
+		return
+	}
+	for _, p := range this.pins {
+		if p.q.String() == this.pattern.String() {
+			this.status = fmt.Sprintf("/%s/ already pinned", p.q)
+			return
+		}
+	}
+	used := map[tcell.Color]bool{}
+	for _, p := range this.pins {
+		used[p.color] = true
+	}
+	col := pinColors[len(this.pins)%len(pinColors)]
+	for _, c := range pinColors {
+		if !used[c] {
+			col = c
+			break
+		}
+	}
+	this.pins = append(this.pins, &pin{q: this.pattern, color: col})
+	this.status = fmt.Sprintf("pinned /%s/", this.pattern)
+	this.pattern = nil
+}
+
+// list the pins, to pick the one n/N move to, or remove them
+func (this *Screen) pinMenu() {
+	this.openMenu(&menu{
+		title: "pinned highlights",
+		help:  "enter: n/N to it, ",
+		items: func() (out []string) {
+			for _, p := range this.pins {
+				out = append(out, fmt.Sprintf("/%s/", p.q))
+			}
+			return
+		},
+		onEnter: func(i int) {
+			this.pattern = this.pins[i].q
+			this.closeMenu()
+			this.status = fmt.Sprintf("n/N to /%s/", this.pattern)
+		},
+		onDelete: func(i int) {
+			this.pins = append(this.pins[:i], this.pins[i+1:]...)
+		},
+	})
+}
+
+// the pins, top right
+func (this *Screen) paintLegend() {
+	w, _ := this.scr.Size()
+	y := 0
+	for _, p := range this.pins {
+		label := fmt.Sprintf(" %s ", p.q)
+		x := w - runewidth.StringWidth(label)
+		if x < 0 {
+			x = 0
+		}
+		cur := this.NewCursor(x, y)
+		cur.Style = tcell.StyleDefault.Background(tcell.Color236).Foreground(p.color).Bold(true)
+		cur.Print(label)
+		y++
+	}
+}
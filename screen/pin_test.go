@@ -0,0 +1,50 @@
+package screen
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/ohait/jl/tbuf"
+)
+
+func TestPins(t *testing.T) {
+	s := simScreen(t, 30, 3)
+	s.pinSearch()
+	if len(s.pins) != 0 {
+		t.Fatalf("nothing to pin")
+	}
+	s.pattern = tbuf.ParseQuery(`ord-1`, tbuf.CaseSensitive)
+	s.pinSearch()
+	s.pattern = tbuf.ParseQuery(`host`, tbuf.CaseSensitive)
+	s.pinSearch()
+	s.pattern = tbuf.ParseQuery(`host`, tbuf.CaseSensitive)
+	s.pinSearch()
+	if len(s.pins) != 2 || s.pins[0].color == s.pins[1].color {
+		t.Fatalf("expected 2 pins with different colors: %+v", s.pins)
+	}
+
+	s.pattern = tbuf.ParseQuery(`time`, tbuf.CaseSensitive)
+	cur := s.NewCursor(0, 0)
+	cur.pattern = s.pattern.Re
+	cur.PrintHL("ord-1 host timeout")
+	fg := func(x int) tcell.Color {
+		_, _, st, _ := s.scr.GetContent(x, 0)
+		c, _, _ := st.Decompose()
+		return c
+	}
+	if fg(0) != s.pins[0].color || fg(6) != s.pins[1].color || fg(11) != tcell.Color87 || fg(5) == s.pins[0].color {
+		t.Fatalf("bad colors: %v %v %v %v", fg(0), fg(6), fg(11), fg(5))
+	}
+
+	s.pinMenu()
+	s.menu.sel = 1
+	s.menu.onEnter(1)
+	if s.pattern != s.pins[1].q || s.menu != nil {
+		t.Fatalf("expected n/N on the second pin")
+	}
+	s.pinMenu()
+	s.menuDelete()
+	if len(s.pins) != 1 || s.pins[0].q.String() != "host" {
+		t.Fatalf("expected the first pin removed: %+v", s.pins)
+	}
+}
@@ -1,10 +1,10 @@
 package screen
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
-	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -16,24 +16,50 @@ import (
 )
 
 type Screen struct {
-	Done      chan struct{}
-	m         sync.Mutex
-	scr       tcell.Screen
-	origBuf   *tbuf.Buffer
-	buffer    *tbuf.Buffer
-	row       int
-	col       int
-	log       func(...interface{})
-	details   int
-	detoffset int
-	help      bool
-	pattern   *regexp.Regexp
-	Refresh   bool
-	input     *util.HistoryInput
-	onEnter   func()
-	onChange  func()
-	query     string
-	queryEnd  string
+	Done         chan struct{}
+	m            sync.Mutex
+	scr          tcell.Screen
+	origBuf      *tbuf.Buffer
+	buffer       *tbuf.Buffer
+	row          int
+	col          int
+	log          func(...interface{})
+	details      int
+	detoffset    int
+	help         bool
+	alpha        bool   // sort tags alphabetically, instead of the source order
+	trusted      bool   // show journal trusted fields
+	threshold    string // hide the lines less severe than this level
+	excludes     []*exclusion
+	filter       filter  // built from threshold and excludes
+	hiddenSearch *search // counts the lines hidden by the filter
+	menu         *menu
+	pins         []*pin // highlighted patterns, besides the search
+	search       *search
+	context      int        // lines before and after the matches, when filtering
+	set          tbuf.Marks // the mark set used, see curSet()
+	ops          undoLog
+	pattern      *tbuf.Query
+	caseMode     tbuf.CaseMode
+	Refresh      bool
+	ANSI         bool               // render the colors of escape sequences, instead of stripping them
+	input        *util.HistoryInput // the input of the current prompt
+	searchInput  *util.HistoryInput
+	timeInput    *util.HistoryInput
+	stepInput    *util.HistoryInput
+	exclInput    *util.HistoryInput
+	setInput     *util.HistoryInput
+	status       string // a message for the last command, until the next key
+	onEnter      func()
+	onChange     func()
+	query        string
+	queryEnd     string
+	rsearch      *util.Input // reverse search in the history (ctrl-r)
+	rsPos        int         // the entry found by the reverse search
+	rsOrig       string      // the input before the reverse search
+	completions  []string    // tab completions shown
+	compSel      int         // the selected completion, or -1
+	compToken    string      // what was typed before completing
 }
 
 func NewScreen(log func(...interface{}), buffer *tbuf.Buffer) *Screen {
@@ -52,10 +78,18 @@ func NewScreen(log func(...interface{}), buffer *tbuf.Buffer) *Screen {
 		log:     log,
 		origBuf: buffer,
 		buffer:  buffer,
-		input:   &util.HistoryInput{},
 		Done:    make(chan struct{}),
 	}
 	this.log("size: %d/%d", w, h)
+	this.searchInput, err = util.NewHistoryInput(&util.HistoryFile{Path: util.StatePath("history"), Max: 1000})
+	if err != nil {
+		this.log("can't load history: %v", err)
+	}
+	this.input = this.searchInput
+	this.timeInput = &util.HistoryInput{}
+	this.stepInput = &util.HistoryInput{}
+	this.exclInput = &util.HistoryInput{}
+	this.setInput = &util.HistoryInput{}
 	//this.pattern = regexp.MustCompile(`lighthouse`)
 	go util.Recover(func() {
 		defer close(this.Done)
@@ -78,6 +112,16 @@ func NewScreen(log func(...interface{}), buffer *tbuf.Buffer) *Screen {
 	return this
 }
 
+// Tick repaints if the buffer changed or a repaint was asked, with the lock
+// held like the events, called periodically by the main loop
+func (this *Screen) Tick(changed bool) {
+	this.m.Lock()
+	defer this.m.Unlock()
+	if changed || this.Refresh {
+		this.Repaint()
+	}
+}
+
 func (this *Screen) handleEvent(ev tcell.Event) error {
 	this.m.Lock()
 	defer this.m.Unlock()
@@ -122,9 +166,9 @@ func (this *Screen) Repaint() {
 		if this.row-y < 0 {
 			break
 		}
-		if line, ok := bc.Up(nil); ok {
+		if line, ok := bc.Up(this.visible); ok {
 			cur := this.NewCursor(-this.col, this.row-y)
-			if this.pattern != nil && !this.pattern.MatchString(line.Str) {
+			if this.pattern != nil && !this.pattern.Match(line) {
 				cur.Style = nomatch
 			}
 			cur.Line(line, this.col)
@@ -142,9 +186,9 @@ func (this *Screen) Repaint() {
 		if y+this.row >= h {
 			break
 		}
-		if line, ok := bc.Down(nil); ok {
+		if line, ok := bc.Down(this.visible); ok {
 			cur := this.NewCursor(-this.col, this.row+y)
-			if this.pattern != nil && !this.pattern.MatchString(line.Str) {
+			if this.pattern != nil && !this.pattern.Match(line) {
 				cur.Style = nomatch
 			}
 			cur.Line(line, this.col)
@@ -160,7 +204,6 @@ func (this *Screen) Repaint() {
 	if line, ok := this.buffer.Get(); ok {
 		//this.log("cur: %+v", line)
 
-		cur.pattern = this.pattern
 		cur = cur.Line(line, this.col)
 		cur.Style = tcell.StyleDefault.Background(tcell.Color236)
 
@@ -169,18 +212,34 @@ func (this *Screen) Repaint() {
 		case 1: // tags
 			cur.X = 24 - this.col
 			cur = cur.Fg(tcell.ColorTeal).Printf(" time: %v, level: %s", line.Time.UTC(), line.Level).Clear()
-			for _, tag := range line.SortedTags() {
+			tags := line.OrderedTags()
+			if this.alpha {
+				tags = line.SortedTags()
+			}
+			for _, tag := range tags {
 				v := line.Tags[tag]
 				if len(v) == 0 {
 					continue
 				}
+				if !this.trusted && line.IsTrusted(tag) {
+					continue
+				}
 				cur.X = 24 - this.col
-				cur = cur.Fg(tcell.ColorOrange).Printf(" %s: ", tag).Fg(tcell.ColorWhite)
-				if len(v) > 40 && v[0] == '{' {
-					var x interface{}
-					err := json.Unmarshal(v, &x)
+				cur = cur.Fg(tcell.ColorOrange).Print(" ")
+				if re := this.pattern.Highlight(tbuf.PartKey, tag); re != nil {
+					cur = cur.printHL(tag, re, cur.Style.Foreground(tcell.Color87).Bold(true), Cursor.Print)
+				} else {
+					cur = cur.Print(tag)
+				}
+				cur = cur.Print(": ").Fg(tcell.ColorWhite)
+				cur.pattern = this.pattern.Highlight(tbuf.PartValue, tag)
+				as, decoded := line.Decoded[tag]
+				if decoded {
+					cur = cur.Fg(tcell.Color244).Printf("(%s) ", as).Fg(tcell.ColorWhite)
+				}
+				if decoded || len(v) > 40 && v[0] == '{' {
+					j, err := this.indent(v)
 					if err == nil {
-						j, _ := json.MarshalIndent(x, "", "  ")
 						lines := strings.Split(string(j), "\n")
 						//this.log("subtag: %q", lines[1])
 						for _, l := range lines[1 : len(lines)-1] {
@@ -208,9 +267,10 @@ func (this *Screen) Repaint() {
 			//cur.X = 24
 			//cur = cur.Col(tcell.ColorTeal).Printf(" level: ").Level(line.Level).Printf(", time: %v", line.Time).Clear()
 			str := line.Short
-			if str == "" {
-				str = line.Str
+			if str == "" || !strings.HasPrefix(line.Str, "{") {
+				str = line.Str // not json, show the whole record
 			}
+			cur.pattern = this.pattern.Highlight(tbuf.PartRaw, "")
 			for _, s := range util.Prettify(str) {
 				cur.X = 24 - this.col
 				cur = cur.PrintfHL(" %s", s).Clear()
@@ -226,6 +286,8 @@ func (this *Screen) Repaint() {
 	}
 
 	// status bar
+	this.syncSearch()
+	this.syncHidden()
 	{
 		cur := this.NewCursor(0, h-1)
 		cur.Style = tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite)
@@ -236,13 +298,48 @@ func (this *Screen) Repaint() {
 		if this.buffer != this.origBuf {
 			cur = cur.Printf(" (orig: %d lines)", len(this.origBuf.Lines))
 		}
+		if this.threshold != "" {
+			cur = cur.Printf(" [%s+]", this.threshold)
+		}
+		if set := this.curSet(); set != 1 {
+			cur = cur.Printf(" set:%v", set)
+		}
+		if this.context > 0 {
+			cur = cur.Printf(" ctx:%d", this.context)
+		}
+		if last := this.ops.last(); last != "" && this.query == "" {
+			cur = cur.Printf(" last: %s", last)
+		}
+		if n, done := this.hidden(); n > 0 {
+			tot := commas(n)
+			if !done {
+				tot += "..."
+			}
+			cur = cur.Printf(" (hidden: %s)", tot)
+		}
 		this.scr.HideCursor()
 		switch this.query {
 		case "":
 			if this.pattern != nil {
-				cur = cur.Printf(" /%+v/", this.pattern)
+				cur = cur.Printf(" /%+v/ %s", this.pattern, this.matchStatus())
 			}
 		case "SEARCH":
+			if this.rsearch != nil {
+				cur = cur.Print(" (reverse-i-search)'")
+				l, r := this.rsearch.Details()
+				cur.Style = cur.Style.Bold(true)
+				cur = cur.Print(l)
+				this.scr.ShowCursor(cur.X, cur.Y)
+				cur = cur.Print(r)
+				cur.Style = cur.Style.Bold(false)
+				if this.rsPos < 0 {
+					cur = cur.Print("' not found: ")
+				} else {
+					cur = cur.Print("': ")
+				}
+				cur = cur.Print(this.input.Get().String())
+				break
+			}
 			cur = cur.Print(" /")
 			l, r := this.input.Get().Details()
 			cur.Style = cur.Style.Bold(true)
@@ -250,7 +347,11 @@ func (this *Screen) Repaint() {
 			this.scr.ShowCursor(cur.X, cur.Y)
 			cur = cur.Print(r)
 			cur.Style = cur.Style.Bold(false)
-			cur = cur.Print("/")
+			cur = cur.Print("/ ")
+			if this.caseMode != tbuf.CaseSensitive {
+				cur = cur.Printf("[%v] ", this.caseMode)
+			}
+			cur = cur.Print(this.matchStatus())
 		default:
 			if this.pattern != nil {
 				cur = cur.Printf(" /%+v/", this.pattern)
@@ -265,9 +366,16 @@ func (this *Screen) Repaint() {
 			cur.Style = cur.Style.Bold(false)
 			cur = cur.Print(this.queryEnd)
 		}
+		if this.status != "" {
+			cur = cur.Print("  ").Fg(tcell.ColorNavy).Print(this.status)
+		}
 		cur.Clear()
 	}
 
+	this.paintLegend()
+	this.paintCompletions()
+	this.paintMenu()
+
 	// HELP
 
 	if this.help {
@@ -277,21 +385,54 @@ func (this *Screen) Repaint() {
 		cur = cur.Printf("   [/] Search                ").CR(20)
 		cur = cur.Printf("   [M] Mark matches          ").CR(20)
 		cur = cur.Printf(" [⇧+M] unmark all            ").CR(20)
+		cur = cur.Printf("   [S] pick the mark set     ").CR(20)
+		cur = cur.Printf(" [⇧+S] combine sets: a & !b  ").CR(20)
 		cur = cur.Printf("   [ ] Mark current line     ").CR(20)
 		cur = cur.Printf("   [G] grep marked           ").CR(20)
 		cur = cur.Printf(" [⇧+G] grep unmarked         ").CR(20)
+		cur = cur.Printf("   [F] grep failures/errors  ").CR(20)
+		cur = cur.Printf("   [E] next error (⇧ prev)   ").CR(20)
+		cur = cur.Printf("   [W] next warning (⇧ prev) ").CR(20)
+		cur = cur.Printf(" [1-4] min level dbg..error  ").CR(20)
+		cur = cur.Printf(" [+ -] context lines for G/F ").CR(20)
 		cur = cur.Printf(" [⇧+O] original buffer       ").CR(20)
 		cur = cur.Printf("   [C] copy current line     ").CR(20)
 		cur = cur.Printf(" [⇧+C] copy marked lines     ").CR(20)
 		cur = cur.Printf("   [0] first line            ").CR(20)
 		cur = cur.Printf(" [⇧+F] tail mode             ").CR(20)
 		cur = cur.Printf("   [D] show details          ").CR(20)
+		cur = cur.Printf("   [_] journal trusted fields").CR(20)
+		cur = cur.Printf("   [A] sort fields by name   ").CR(20)
+		cur = cur.Printf(" [⇧+R] render ansi colors    ").CR(20)
+		cur = cur.Printf("   [T] jump to time, or A..B ").CR(20)
+		cur = cur.Printf(" [⇧+T] time step             ").CR(20)
+		cur = cur.Printf(" [[ ]] step back/forward     ").CR(20)
+		cur = cur.Printf("   [P] pin the search        ").CR(20)
+		cur = cur.Printf(" [⇧+P] list pins, pick n/N   ").CR(20)
+		cur = cur.Printf("   [&] exclude a pattern     ").CR(20)
+		cur = cur.Printf(" [⇧+X] list exclusions       ").CR(20)
+		cur = cur.Printf("   [U] undo (⇧ redo)         ").CR(20)
 		cur = cur.Printf("   [Q] quit                  ").CR(20)
 	}
 
 	this.scr.Show()
 }
 
+// indent a json value, in source order or sorted
+func (this *Screen) indent(v json.RawMessage) ([]byte, error) {
+	if this.alpha {
+		var x interface{}
+		err := json.Unmarshal(v, &x)
+		if err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(x, "", "  ")
+	}
+	b := &bytes.Buffer{}
+	err := json.Indent(b, v, "", "  ")
+	return b.Bytes(), err
+}
+
 func undoTcellSig() error {
 	tio, err := unix.IoctlGetTermios(int(os.Stdout.Fd()), GET_TERMIOS)
 	if err != nil {
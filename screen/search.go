@@ -0,0 +1,238 @@
+package screen
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ohait/jl/tbuf"
+	"github.com/ohait/jl/util"
+)
+
+// how many lines are copied from the buffer at a time
+const searchChunk = 10000
+
+// the matches of a pattern (or the lines hidden by a filter), found in the background
+type search struct {
+	buffer  *tbuf.Buffer
+	pattern *tbuf.Query // nil when counting the hidden lines
+	filter  filter
+	match   func(l tbuf.Line) bool
+	onMore  func() // called from the search goroutine when a chunk is done
+	log     func(...interface{})
+	stop    chan struct{}
+	once    sync.Once // to close stop
+
+	m       sync.Mutex
+	pos     []int // the matching lines, sorted
+	upto    int   // how many lines have been scanned
+	running bool
+}
+
+func newSearch(b *tbuf.Buffer, p *tbuf.Query, f filter, onMore func(), log func(...interface{})) *search {
+	return &search{
+		buffer:  b,
+		pattern: p,
+		filter:  f,
+		match: func(l tbuf.Line) bool {
+			return f.visible(l) && p.Match(l)
+		},
+		onMore: onMore,
+		log:    log,
+		stop:   make(chan struct{}),
+	}
+}
+
+// the lines hidden by the filter
+func newHiddenSearch(b *tbuf.Buffer, f filter, onMore func(), log func(...interface{})) *search {
+	return &search{
+		buffer: b,
+		filter: f,
+		match: func(l tbuf.Line) bool {
+			return !f.visible(l)
+		},
+		onMore: onMore,
+		log:    log,
+		stop:   make(chan struct{}),
+	}
+}
+
+// start scanning the lines not scanned yet, if not already
+func (this *search) start() {
+	this.m.Lock()
+	defer this.m.Unlock()
+	if this.running || this.upto >= this.buffer.Size() {
+		return
+	}
+	this.running = true
+	go util.Recover(this.scan, func(r interface{}, stack string) {
+		this.log("search panic: %v\n%s", r, stack)
+	})
+}
+
+func (this *search) scan() {
+	for {
+		select {
+		case <-this.stop:
+			return
+		default:
+		}
+		this.m.Lock()
+		from := this.upto
+		this.m.Unlock()
+		lines := this.buffer.Slice(from, from+searchChunk)
+		var found []int
+		for i, l := range lines {
+			if this.match(l) {
+				found = append(found, from+i)
+			}
+		}
+		this.m.Lock()
+		this.pos = append(this.pos, found...)
+		this.upto = from + len(lines)
+		this.m.Unlock()
+		if this.onMore != nil {
+			this.onMore()
+		}
+		if len(lines) == 0 { // done only after onMore, so it's not called after Count() says so
+			this.m.Lock()
+			this.running = false
+			this.m.Unlock()
+			return
+		}
+	}
+}
+
+// cancel the scan, it can be called more than once
+func (this *search) Cancel() {
+	this.once.Do(func() { close(this.stop) })
+}
+
+// Count returns the matches found so far, and if the whole buffer has been scanned
+func (this *search) Count() (int, bool) {
+	this.m.Lock()
+	defer this.m.Unlock()
+	return len(this.pos), !this.running && this.upto >= this.buffer.Size()
+}
+
+// Index returns the 1-based index of the match at pos, or 0
+func (this *search) Index(pos int) int {
+	this.m.Lock()
+	defer this.m.Unlock()
+	i := sort.SearchInts(this.pos, pos)
+	if i < len(this.pos) && this.pos[i] == pos {
+		return i + 1
+	}
+	return 0
+}
+
+// Next returns the first match after pos (or before, if dir < 0), or -1.
+// It's not known yet if the lines in between haven't been scanned
+func (this *search) Next(pos, dir int) (int, bool) {
+	this.m.Lock()
+	defer this.m.Unlock()
+	scanned := this.upto >= this.buffer.Size()
+	if dir > 0 {
+		i := sort.SearchInts(this.pos, pos+1)
+		if i < len(this.pos) {
+			return this.pos[i], true
+		}
+		return -1, scanned
+	}
+	if this.upto < pos {
+		return -1, false
+	}
+	i := sort.SearchInts(this.pos, pos)
+	if i > 0 {
+		return this.pos[i-1], true
+	}
+	return -1, true
+}
+
+// restart the search if the pattern, the buffer or the filters changed,
+// or continue it over the new lines
+func (this *Screen) syncSearch() {
+	s := this.search
+	if s != nil && (s.pattern != this.pattern || s.buffer != this.buffer || s.filter.gen != this.filter.gen) {
+		s.Cancel()
+		this.search, s = nil, nil
+	}
+	if this.pattern == nil {
+		return
+	}
+	if s == nil {
+		s = newSearch(this.buffer, this.pattern, this.filter, this.refreshLater, this.log)
+		this.search = s
+	}
+	s.start()
+}
+
+// ask for a repaint, from a background goroutine
+func (this *Screen) refreshLater() {
+	this.m.Lock()
+	defer this.m.Unlock()
+	this.Refresh = true
+}
+
+// move to the next (dir > 0) or previous match or marked line
+func (this *Screen) nextMatch(dir int) {
+	this.syncSearch()
+	pos := this.buffer.Pos
+	target := -1
+	if this.search != nil {
+		var known bool
+		target, known = this.search.Next(pos, dir)
+		if !known {
+			this.status = "still searching..."
+			return
+		}
+	}
+	// a marked line before the next match
+	limit := target
+	if limit < 0 {
+		limit = this.buffer.Size()
+		if dir < 0 {
+			limit = -1
+		}
+	}
+	set := this.curSet()
+	mark := this.buffer.Find(pos+dir, limit, func(l tbuf.Line) bool {
+		return l.Marks&set != 0 && this.visible(l)
+	})
+	if mark >= 0 {
+		target = mark
+	}
+	if target < 0 {
+		this.status = "no more matches"
+		return
+	}
+	this.jump(target)
+}
+
+// match 17/2,341 in the status bar
+func (this *Screen) matchStatus() string {
+	if this.search == nil {
+		return ""
+	}
+	n, done := this.search.Count()
+	tot := commas(n)
+	if !done {
+		tot += "..."
+	}
+	if i := this.search.Index(this.buffer.Pos); i > 0 {
+		return fmt.Sprintf("match %s/%s", commas(i), tot)
+	}
+	return fmt.Sprintf("%s matches", tot)
+}
+
+// 1234567 as 1,234,567
+func commas(n int) string {
+	s := fmt.Sprint(n)
+	if n < 0 {
+		return "-" + commas(-n)
+	}
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
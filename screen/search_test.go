@@ -0,0 +1,119 @@
+package screen
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell"
+	"github.com/ohait/jl/tbuf"
+)
+
+func waitSearch(t *testing.T, s *search) int {
+	for i := 0; i < 500; i++ {
+		if n, done := s.Count(); done {
+			return n
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("search not done")
+	return 0
+}
+
+func TestSearch(t *testing.T) {
+	b := &tbuf.Buffer{}
+	for i := 0; i < 3*searchChunk; i++ {
+		b.Append(fmt.Sprintf("line %d", i), t.Log)
+	}
+	s := newSearch(b, tbuf.ParseQuery(`7$`, tbuf.CaseSensitive), filter{}, nil, t.Log)
+	s.start()
+	if n := waitSearch(t, s); n != 3*searchChunk/10 {
+		t.Fatalf("expected %d matches, got %d", 3*searchChunk/10, n)
+	}
+	if p, ok := s.Next(7, 1); p != 17 || !ok {
+		t.Errorf("expected 17, got %d %v", p, ok)
+	}
+	if p, ok := s.Next(17, -1); p != 7 || !ok {
+		t.Errorf("expected 7, got %d %v", p, ok)
+	}
+	if p, ok := s.Next(7, -1); p != -1 || !ok {
+		t.Errorf("expected none, got %d %v", p, ok)
+	}
+	if i := s.Index(27); i != 3 {
+		t.Errorf("expected the 3rd match, got %d", i)
+	}
+
+	b.Append("line 7", t.Log)
+	if _, done := s.Count(); done {
+		t.Fatalf("expected the new line not scanned yet")
+	}
+	s.start()
+	if n := waitSearch(t, s); n != 3*searchChunk/10+1 {
+		t.Fatalf("expected the new line to match, got %d", n)
+	}
+	s.Cancel()
+	s.Cancel()
+}
+
+// the main loop repaints while the events are handled
+func TestTickEvents(t *testing.T) {
+	s := simScreen(t, 40, 10)
+	s.log = t.Log
+	s.buffer = &tbuf.Buffer{}
+	s.origBuf = s.buffer
+	for i := 0; i < 1000; i++ {
+		s.buffer.Append(fmt.Sprintf("line %d", i), t.Log)
+	}
+	s.pattern = tbuf.ParseQuery(`7`, tbuf.CaseSensitive)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			s.Tick(true)
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		s.handleEvent(tcell.NewEventKey(tcell.KeyRune, rune("4n"[i%2]), 0))
+	}
+	<-done
+}
+
+func TestNextMatch(t *testing.T) {
+	s := simScreen(t, 40, 10)
+	s.buffer = &tbuf.Buffer{}
+	for _, l := range []string{"a", "foo", "b", "c", "foo", "d"} {
+		s.buffer.Append(l, t.Log)
+	}
+	s.buffer.Pos = 0
+	s.buffer.Lines[3].Marks = 1
+	s.pattern = tbuf.ParseQuery(`foo`, tbuf.CaseSensitive)
+	s.syncSearch()
+	waitSearch(t, s.search)
+
+	for _, exp := range []int{1, 3, 4} {
+		s.nextMatch(1)
+		if s.buffer.Pos != exp {
+			t.Fatalf("expected %d, got %d", exp, s.buffer.Pos)
+		}
+	}
+	if st := s.matchStatus(); st != "match 2/2" {
+		t.Errorf("bad status %q", st)
+	}
+	s.nextMatch(1)
+	if s.buffer.Pos != 4 || s.status != "no more matches" {
+		t.Fatalf("expected to stay, got %d %q", s.buffer.Pos, s.status)
+	}
+	s.nextMatch(-1)
+	if s.buffer.Pos != 3 {
+		t.Fatalf("expected the mark, got %d", s.buffer.Pos)
+	}
+	s.search.Cancel()
+}
+
+func TestCommas(t *testing.T) {
+	for n, exp := range map[int]string{0: "0", 999: "999", 1000: "1,000", 2341: "2,341", 1234567: "1,234,567", -1000: "-1,000"} {
+		if got := commas(n); got != exp {
+			t.Errorf("%d: expected %q, got %q", n, exp, got)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package screen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ohait/jl/tbuf"
+	"github.com/ohait/jl/util"
+)
+
+// how much [ and ] move in time
+var TimeStep = time.Minute
+
+var reClock = regexp.MustCompile(`^(\d{1,2}):(\d{2})(?::(\d{2})(\.\d+)?)?$`)
+
+// parse a time typed by the user:
+//
+//	-15m, +1h    relative to ref
+//	10:42        UTC, on the same day of ref
+//	2024-03-01T10:42Z, or any layout known to tbuf
+func parseTimeQuery(s string, ref time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, fmt.Errorf("empty time")
+	}
+	if s[0] == '-' || s[0] == '+' {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return ref.Add(d), nil
+	}
+	if m := reClock.FindStringSubmatch(s); m != nil {
+		var h, min, sec int
+		fmt.Sscan(m[1], &h)
+		fmt.Sscan(m[2], &min)
+		fmt.Sscan(m[3], &sec)
+		if h > 23 || min > 59 || sec > 59 {
+			return time.Time{}, fmt.Errorf("bad time %q", s)
+		}
+		var ns time.Duration
+		if m[4] != "" {
+			d, _ := time.ParseDuration("0" + m[4] + "s")
+			ns = d
+		}
+		ref = ref.In(tbuf.Location) // like the timestamps without a zone
+		return time.Date(ref.Year(), ref.Month(), ref.Day(), h, min, sec, 0, tbuf.Location).Add(ns), nil
+	}
+	if t, ok := tbuf.ParseTimeString(s); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("bad time %q", s)
+}
+
+// parse a window "A..B", where a relative B is from A, and either can be empty
+func parseTimeWindow(s string, ref time.Time) (from, to time.Time, err error) {
+	parts := strings.SplitN(s, "..", 2)
+	if strings.TrimSpace(parts[0]) != "" {
+		from, err = parseTimeQuery(parts[0], ref)
+		if err != nil {
+			return
+		}
+	}
+	if strings.TrimSpace(parts[1]) != "" {
+		if !from.IsZero() {
+			ref = from
+		}
+		to, err = parseTimeQuery(parts[1], ref)
+		if err != nil {
+			return
+		}
+	}
+	if from.IsZero() && to.IsZero() {
+		err = fmt.Errorf("empty window")
+	} else if !to.IsZero() && to.Before(from) {
+		err = fmt.Errorf("window ends before it starts")
+	}
+	return
+}
+
+// the time relative queries start from: the newest line, or now
+func (this *Screen) refTime() time.Time {
+	if t := this.buffer.Latest(); !t.IsZero() {
+		return t
+	}
+	return time.Now()
+}
+
+// move to the given line, keeping the cursor row on screen
+func (this *Screen) jump(pos int) {
+	_, h := this.scr.Size()
+	this.detoffset = 0
+	this.buffer.Pos = pos
+	this.settle()
+	if this.row > pos {
+		this.row = pos
+	}
+	if this.row > h-2 {
+		this.row = h - 2
+	}
+}
+
+// jump to a time, or build a window view for A..B
+func (this *Screen) gotoTime(s string) {
+	if strings.Contains(s, "..") {
+		from, to, err := parseTimeWindow(s, this.refTime())
+		if err != nil {
+			this.status = err.Error()
+			return
+		}
+		b := this.buffer.Window(from, to)
+		if len(b.Lines) == 0 {
+			this.status = "no lines in the window"
+			return
+		}
+		this.status = fmt.Sprintf("window %s", s)
+		this.setBuffer(this.status, b)
+		this.row = 0
+		this.jump(0)
+		return
+	}
+	t, err := parseTimeQuery(s, this.refTime())
+	if err != nil {
+		this.status = err.Error()
+		return
+	}
+	this.jump(this.buffer.Seek(t))
+	this.status = fmt.Sprintf("at %s", t.UTC().Format(time.RFC3339))
+}
+
+// move by TimeStep from the time of the current line
+func (this *Screen) stepTime(dir int) {
+	t := this.buffer.TimeAt(this.buffer.Pos)
+	if t.IsZero() {
+		this.status = "no time on this line"
+		return
+	}
+	pos := this.buffer.Seek(t.Add(time.Duration(dir) * TimeStep))
+	if dir < 0 && pos >= this.buffer.Pos && this.buffer.Pos > 0 {
+		pos = this.buffer.Pos - 1 // more lines at the same time
+	}
+	this.jump(pos)
+	this.status = fmt.Sprintf("%+v", time.Duration(dir)*TimeStep)
+}
+
+// open a prompt in the status bar
+func (this *Screen) prompt(label string, in *util.HistoryInput, onEnter func(s string)) {
+	this.query = label
+	this.queryEnd = ""
+	this.input = in
+	this.input.NewUnlessEmpty()
+	this.onChange = func() {}
+	this.onEnter = func() {
+		this.query = ""
+		s := this.input.Get().String()
+		this.input.Commit()
+		onEnter(s)
+	}
+}
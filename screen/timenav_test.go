@@ -0,0 +1,62 @@
+package screen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ohait/jl/tbuf"
+)
+
+func TestParseTimeQuery(t *testing.T) {
+	tbuf.Location = time.UTC
+	defer func() { tbuf.Location = time.Local }()
+	ref := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	for s, exp := range map[string]time.Time{
+		"-15m":              time.Date(2024, 3, 1, 12, 15, 0, 0, time.UTC),
+		"+1h":               time.Date(2024, 3, 1, 13, 30, 0, 0, time.UTC),
+		"10:42":             time.Date(2024, 3, 1, 10, 42, 0, 0, time.UTC),
+		"10:42:05.5":        time.Date(2024, 3, 1, 10, 42, 5, 5e8, time.UTC),
+		"2024-03-01T10:42Z": time.Date(2024, 3, 1, 10, 42, 0, 0, time.UTC),
+	} {
+		got, err := parseTimeQuery(s, ref)
+		if err != nil {
+			t.Errorf("%q: %v", s, err)
+			continue
+		}
+		if !got.Equal(exp) {
+			t.Errorf("%q: expected %v, got %v", s, exp, got)
+		}
+	}
+	for _, s := range []string{"", "25:00", "soon", "-15x"} {
+		if _, err := parseTimeQuery(s, ref); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+
+	// in the zone of the timestamps, on the day of ref there
+	tbuf.Location = time.FixedZone("X", 14*3600)
+	got, err := parseTimeQuery("10:42", ref)
+	if exp := time.Date(2024, 3, 1, 20, 42, 0, 0, time.UTC); err != nil || !got.Equal(exp) {
+		t.Errorf("expected %v, got %v", exp, got.UTC())
+	}
+}
+
+func TestParseTimeWindow(t *testing.T) {
+	tbuf.Location = time.UTC
+	defer func() { tbuf.Location = time.Local }()
+	ref := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	from, to, err := parseTimeWindow("10:40..+5m", ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !from.Equal(time.Date(2024, 3, 1, 10, 40, 0, 0, time.UTC)) || !to.Equal(time.Date(2024, 3, 1, 10, 45, 0, 0, time.UTC)) {
+		t.Errorf("bad window: %v..%v", from, to)
+	}
+	from, to, err = parseTimeWindow("-1h..", ref)
+	if err != nil || !to.IsZero() || !from.Equal(ref.Add(-time.Hour)) {
+		t.Errorf("bad open window: %v..%v %v", from, to, err)
+	}
+	if _, _, err = parseTimeWindow("11:00..10:00", ref); err == nil {
+		t.Errorf("expected an error for a reversed window")
+	}
+}
@@ -0,0 +1,218 @@
+package screen
+
+import (
+	"fmt"
+
+	"github.com/ohait/jl/tbuf"
+)
+
+// how many operations can be undone
+const undoMax = 100
+
+// an operation which can be undone
+type op struct {
+	name string
+	undo func()
+	redo func()
+}
+
+// the operations done, and the ones undone which can be redone
+type undoLog struct {
+	done   []op
+	undone []op
+}
+
+func (this *undoLog) push(o op) {
+	this.done = append(this.done, o)
+	if len(this.done) > undoMax {
+		this.done = this.done[len(this.done)-undoMax:]
+	}
+	this.undone = nil
+}
+
+func (this *undoLog) undo() (op, bool) {
+	if len(this.done) == 0 {
+		return op{}, false
+	}
+	o := this.done[len(this.done)-1]
+	this.done = this.done[:len(this.done)-1]
+	this.undone = append(this.undone, o)
+	return o, true
+}
+
+func (this *undoLog) redo() (op, bool) {
+	if len(this.undone) == 0 {
+		return op{}, false
+	}
+	o := this.undone[len(this.undone)-1]
+	this.undone = this.undone[:len(this.undone)-1]
+	this.done = append(this.done, o)
+	return o, true
+}
+
+// the name of the last operation done, or ""
+func (this *undoLog) last() string {
+	if len(this.done) == 0 {
+		return ""
+	}
+	return this.done[len(this.done)-1].name
+}
+
+func (this *Screen) undo() {
+	o, ok := this.ops.undo()
+	if !ok {
+		this.status = "nothing to undo"
+		return
+	}
+	o.undo()
+	this.status = "undone: " + o.name
+}
+
+func (this *Screen) redo() {
+	o, ok := this.ops.redo()
+	if !ok {
+		this.status = "nothing to redo"
+		return
+	}
+	o.redo()
+	this.status = "redone: " + o.name
+}
+
+// a line whose marks changed
+type markChange struct {
+	pos           int
+	before, after tbuf.Marks
+}
+
+// change the marks of the lines of the buffer (not the dividers), so it can be
+// undone. Returns how many lines changed
+func (this *Screen) mark(name string, f func(l *tbuf.Line)) int {
+	b := this.buffer
+	var changes []markChange
+	b.Range(func(i int, l *tbuf.Line) bool {
+		if l.Divider {
+			return true
+		}
+		before := l.Marks
+		f(l)
+		if l.Marks != before {
+			changes = append(changes, markChange{i, before, l.Marks})
+		}
+		return true
+	})
+	this.pushMarks(fmt.Sprintf("%s (%d lines)", name, len(changes)), changes)
+	return len(changes)
+}
+
+// toggle the current line in the mark set
+func (this *Screen) markCurrent(set tbuf.Marks) {
+	l, ok := this.buffer.Get()
+	if !ok || l.Divider {
+		return
+	}
+	pos := this.buffer.Pos
+	this.buffer.Mark(set)
+	verb := "mark"
+	if l.Marks&set != 0 {
+		verb = "unmark"
+	}
+	this.pushMarks(fmt.Sprintf("%s line %d", verb, pos), []markChange{{pos, l.Marks, l.Marks ^ set}})
+}
+
+// the marks of the current buffer changed. Undo and redo show that buffer
+// again, to see what they change
+func (this *Screen) pushMarks(name string, changes []markChange) {
+	if len(changes) == 0 {
+		return
+	}
+	v := this.view()
+	this.ops.push(op{
+		name: name,
+		undo: func() {
+			this.showView(v)
+			for _, c := range changes {
+				v.buffer.SetMarks(c.pos, c.before)
+			}
+		},
+		redo: func() {
+			this.showView(v)
+			for _, c := range changes {
+				v.buffer.SetMarks(c.pos, c.after)
+			}
+		},
+	})
+}
+
+// what is shown, besides the current line which is kept by the buffer
+type view struct {
+	buffer    *tbuf.Buffer
+	row       int
+	detoffset int
+}
+
+func (this *Screen) view() view {
+	return view{this.buffer, this.row, this.detoffset}
+}
+
+// show the buffer of v as it was, unless already shown
+func (this *Screen) showView(v view) {
+	if this.buffer != v.buffer {
+		this.buffer, this.row, this.detoffset = v.buffer, v.row, v.detoffset
+	}
+}
+
+// show another buffer, so it can be undone
+func (this *Screen) setBuffer(name string, b *tbuf.Buffer) {
+	prev := this.view()
+	this.buffer = b
+	this.detoffset = 0
+	next := this.view()
+	this.ops.push(op{
+		name: name,
+		undo: func() {
+			next = this.view()
+			this.showView(prev)
+		},
+		redo: func() {
+			prev = this.view()
+			this.showView(next)
+		},
+	})
+}
+
+// the state of the filters, to undo their changes
+type filterState struct {
+	threshold string
+	excludes  []exclusion
+}
+
+func (this *Screen) saveFilters() (out filterState) {
+	out.threshold = this.threshold
+	for _, x := range this.excludes {
+		out.excludes = append(out.excludes, *x)
+	}
+	return
+}
+
+func (this *Screen) restoreFilters(s filterState) {
+	this.threshold = s.threshold
+	this.excludes = nil
+	for _, x := range s.excludes {
+		x := x
+		this.excludes = append(this.excludes, &x)
+	}
+	this.filtersChanged()
+}
+
+// change the filters, so it can be undone
+func (this *Screen) changeFilters(name string, f func()) {
+	before := this.saveFilters()
+	f()
+	after := this.saveFilters()
+	this.filtersChanged()
+	this.ops.push(op{
+		name: name,
+		undo: func() { this.restoreFilters(before) },
+		redo: func() { this.restoreFilters(after) },
+	})
+}
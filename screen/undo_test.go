@@ -0,0 +1,117 @@
+package screen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ohait/jl/tbuf"
+)
+
+func TestUndo(t *testing.T) {
+	s := simScreen(t, 60, 20)
+	s.buffer = &tbuf.Buffer{}
+	for _, l := range []string{"a", "b foo", "c", "d foo"} {
+		s.buffer.Append(l, t.Log)
+	}
+	s.origBuf = s.buffer
+	marked := func() (out string) {
+		for _, l := range s.buffer.Lines {
+			if l.Marks != 0 {
+				out += l.Str[:1]
+			}
+		}
+		return
+	}
+
+	s.buffer.Pos = 0
+	s.markCurrent(s.curSet())
+	n := s.mark("mark foo", func(l *tbuf.Line) {
+		if strings.Contains(l.Str, "foo") {
+			l.Marks |= 1
+		}
+	})
+	if n != 2 || marked() != "abd" {
+		t.Fatalf("expected abd marked, got %d %q", n, marked())
+	}
+	if s.ops.last() != "mark foo (2 lines)" {
+		t.Fatalf("bad last op %q", s.ops.last())
+	}
+	s.mark("unmark all", func(l *tbuf.Line) { l.Marks = 0 })
+	s.undo()
+	if marked() != "abd" || s.status != "undone: unmark all (3 lines)" {
+		t.Fatalf("expected the marks back, got %q %q", marked(), s.status)
+	}
+	s.undo()
+	s.undo()
+	if marked() != "" {
+		t.Fatalf("expected no marks, got %q", marked())
+	}
+	s.undo()
+	if s.status != "nothing to undo" {
+		t.Fatalf("bad status %q", s.status)
+	}
+	s.redo()
+	if marked() != "a" {
+		t.Fatalf("expected a redone, got %q", marked())
+	}
+
+	s.setBuffer("grep", s.buffer.Filter(func(l tbuf.Line) bool { return l.Marks != 0 }))
+	if len(s.buffer.Lines) != 1 {
+		t.Fatalf("expected the filtered buffer")
+	}
+	s.redo()
+	if s.status != "nothing to redo" {
+		t.Fatalf("expected a new op to clear the redos, got %q", s.status)
+	}
+	s.undo()
+	if s.buffer != s.origBuf {
+		t.Fatalf("expected the original buffer back")
+	}
+
+	s.addExclude("foo")
+	s.setThreshold("error")
+	s.undo()
+	s.undo()
+	if len(s.excludes) != 0 || s.threshold != "" || waitHidden(t, s) != 0 {
+		t.Fatalf("expected the filters undone")
+	}
+	s.redo()
+	if len(s.excludes) != 1 || waitHidden(t, s) != 2 {
+		t.Fatalf("expected the exclusion back, hidden %d", waitHidden(t, s))
+	}
+}
+
+func TestUndoView(t *testing.T) {
+	s := simScreen(t, 60, 20)
+	s.buffer = &tbuf.Buffer{}
+	for _, l := range []string{"a", "b foo", "c", "d foo"} {
+		s.buffer.Append(l, t.Log)
+	}
+	s.origBuf = s.buffer
+	s.row, s.detoffset = 7, 3
+
+	grep := s.buffer.Filter(func(l tbuf.Line) bool { return strings.Contains(l.Str, "foo") })
+	s.setBuffer("grep foo", grep)
+	if s.detoffset != 0 {
+		t.Fatalf("expected the details scrolled back")
+	}
+	s.row, s.detoffset = 2, 1
+	s.buffer.Pos = 1
+	s.markCurrent(1)
+
+	s.undo()
+	s.undo()
+	if s.buffer != s.origBuf || s.row != 7 || s.detoffset != 3 {
+		t.Fatalf("expected the original view back, got row %d offset %d", s.row, s.detoffset)
+	}
+	s.redo()
+	if s.buffer != grep || s.row != 2 || s.detoffset != 1 {
+		t.Fatalf("expected the grep view back, got row %d offset %d", s.row, s.detoffset)
+	}
+	s.undo()
+	s.redo()
+	s.redo()
+	if s.buffer != grep || grep.Lines[1].Marks != 1 {
+		t.Fatalf("expected the mark redone on the grep buffer")
+	}
+}
@@ -6,10 +6,24 @@ import (
 )
 
 type Buffer struct {
-	m     sync.Mutex
-	Lines []Line
-	Pos   int
-	Last  time.Time
+	m        sync.Mutex
+	Lines    []Line
+	Pos      int
+	Last     time.Time
+	latest   time.Time // the most recent time of the lines
+	unsorted bool      // some lines are older than the previous ones
+}
+
+// append a line, must be locked
+func (this *Buffer) push(l Line) {
+	if !l.Time.IsZero() {
+		if l.Time.Before(this.latest) {
+			this.unsorted = true
+		} else {
+			this.latest = l.Time
+		}
+	}
+	this.Lines = append(this.Lines, l)
 }
 
 func (this *Buffer) Size() int {
@@ -34,7 +48,8 @@ func (this *Buffer) Range(f func(i int, l *Line) bool) {
 	}
 }
 
-func (this *Buffer) Mark() {
+// Mark toggles the current line in the set
+func (this *Buffer) Mark(set Marks) {
 	this.m.Lock()
 	defer this.m.Unlock()
 	if len(this.Lines) == 0 {
@@ -46,7 +61,10 @@ func (this *Buffer) Mark() {
 	if this.Pos < 0 {
 		return
 	}
-	this.Lines[this.Pos].Mark = !this.Lines[this.Pos].Mark
+	if this.Lines[this.Pos].Divider {
+		return
+	}
+	this.Lines[this.Pos].Marks ^= set
 }
 
 func (this *Buffer) Get() (Line, bool) {
@@ -85,3 +103,124 @@ func (this *Buffer) Up(f func(s Line) bool) (Line, bool) {
 	this.Pos = cur.Cur
 	return l, ok
 }
+
+// Filter returns a new buffer with only the matching lines, unmarked
+func (this *Buffer) Filter(f func(l Line) bool) *Buffer {
+	b := &Buffer{}
+	this.Range(func(i int, l *Line) bool {
+		if i == this.Pos {
+			b.Pos = len(b.Lines)
+		}
+		if !l.Divider && f(*l) {
+			l := *l
+			l.Marks = 0
+			l.Context = false
+			b.push(l)
+		}
+		return true
+	})
+	return b
+}
+
+// FilterContext is like Filter, but keeps also n lines before and after the
+// matching ones, as Context, with a Divider between the groups
+func (this *Buffer) FilterContext(f func(l Line) bool, n int) *Buffer {
+	if n <= 0 {
+		return this.Filter(f)
+	}
+	this.m.Lock()
+	defer this.m.Unlock()
+	const (
+		skip = iota
+		context
+		hit
+	)
+	keep := make([]byte, len(this.Lines))
+	for i, l := range this.Lines {
+		if l.Divider || !f(l) {
+			continue
+		}
+		keep[i] = hit
+		for j := i - n; j <= i+n; j++ {
+			if j >= 0 && j < len(keep) && keep[j] == skip && !this.Lines[j].Divider {
+				keep[j] = context
+			}
+		}
+	}
+	b := &Buffer{Pos: -1}
+	last := -1
+	for i, k := range keep {
+		if k == skip {
+			continue
+		}
+		if last >= 0 && i > last+1 {
+			b.push(Line{Str: "--", Divider: true})
+		}
+		if b.Pos < 0 && i >= this.Pos { // the current line, or the next kept
+			b.Pos = len(b.Lines)
+		}
+		l := this.Lines[i]
+		l.Marks = 0
+		l.Context = k == context
+		b.push(l)
+		last = i
+	}
+	if b.Pos < 0 {
+		b.Pos = len(b.Lines)
+	}
+	return b
+}
+
+// Slice returns a copy of the lines between from and to
+func (this *Buffer) Slice(from, to int) []Line {
+	this.m.Lock()
+	defer this.m.Unlock()
+	if to > len(this.Lines) {
+		to = len(this.Lines)
+	}
+	if from < 0 {
+		from = 0
+	}
+	if from >= to {
+		return nil
+	}
+	return append([]Line(nil), this.Lines[from:to]...)
+}
+
+// Find returns the first line matching f from from towards to (excluded),
+// backward if to < from, or -1. The buffer is locked once for the whole scan
+func (this *Buffer) Find(from, to int, f func(l Line) bool) int {
+	this.m.Lock()
+	defer this.m.Unlock()
+	if from < 0 {
+		from = 0
+	}
+	if to > len(this.Lines) {
+		to = len(this.Lines)
+	}
+	if from >= len(this.Lines) {
+		from = len(this.Lines) - 1
+	}
+	if to < -1 {
+		to = -1
+	}
+	dir := 1
+	if to < from {
+		dir = -1
+	}
+	for i := from; i != to && i >= 0 && i < len(this.Lines); i += dir {
+		if f(this.Lines[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetMarks sets the marks of a line, if it exists and it's not a divider
+func (this *Buffer) SetMarks(pos int, m Marks) {
+	this.m.Lock()
+	defer this.m.Unlock()
+	if pos >= 0 && pos < len(this.Lines) && !this.Lines[pos].Divider {
+		this.Lines[pos].Marks = m
+	}
+}
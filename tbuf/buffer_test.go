@@ -61,8 +61,85 @@ func TestBuffer(t *testing.T) {
 		t.Fatal(b.Pos)
 	}
 
-	b.Up(func(s Line) bool { return strings.Contains(s.Str, "none") })
-	if b.Pos != 0 {
-		t.Fatal(b.Pos)
+	_, ok := b.Up(func(s Line) bool { return strings.Contains(s.Str, "none") })
+	if b.Pos != 0 || ok {
+		t.Fatal(b.Pos, ok)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	b := &Buffer{}
+	for _, s := range []string{"a1", "b2", "a3", "b4", "a5"} {
+		b.Append(s, t.Log)
+	}
+	b.Pos = 3
+	f := b.Filter(func(l Line) bool { return strings.HasPrefix(l.Str, "a") })
+	if len(f.Lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(f.Lines))
+	}
+	if f.Pos != 2 || f.Lines[f.Pos].Str != "a5" {
+		t.Fatalf("expected pos on the next match, got %d", f.Pos)
+	}
+}
+
+func TestFind(t *testing.T) {
+	b := &Buffer{}
+	for _, s := range []string{"a0", "b1", "a2", "b3", "a4"} {
+		b.Append(s, t.Log)
+	}
+	isB := func(l Line) bool { return strings.HasPrefix(l.Str, "b") }
+	for _, c := range []struct{ from, to, exp int }{
+		{0, 5, 1},
+		{2, 5, 3},
+		{4, 5, -1},
+		{4, -1, 3},
+		{2, -1, 1},
+		{0, -1, -1},
+		{2, 3, -1},
+		{10, -1, 3},
+	} {
+		if got := b.Find(c.from, c.to, isB); got != c.exp {
+			t.Errorf("Find(%d, %d): expected %d, got %d", c.from, c.to, c.exp, got)
+		}
+	}
+}
+
+func TestFilterContext(t *testing.T) {
+	b := &Buffer{}
+	for _, s := range []string{"0", "1", "2 hit", "3", "4", "5", "6", "7 hit", "8 hit", "9"} {
+		b.Append(s, t.Log)
+	}
+	b.Pos = 5
+	f := b.FilterContext(func(l Line) bool { return strings.HasSuffix(l.Str, "hit") }, 1)
+	got := []string{}
+	for _, l := range f.Lines {
+		s := l.Str
+		if l.Context {
+			s = "(" + s + ")"
+		}
+		got = append(got, s)
+	}
+	exp := "(1) 2 hit (3) -- (6) 7 hit 8 hit (9)"
+	if strings.Join(got, " ") != exp {
+		t.Fatalf("expected %q, got %q", exp, strings.Join(got, " "))
+	}
+	if f.Pos != 4 || f.Lines[f.Pos].Str != "6" {
+		t.Fatalf("expected pos on the next kept line, got %d", f.Pos)
+	}
+
+	// the divider is not a line: not matched, not marked
+	if ParseQuery("-", CaseSensitive).Match(f.Lines[3]) {
+		t.Errorf("expected the divider not to match")
+	}
+	f.Pos = 3
+	f.Mark(1)
+	f.SetMarks(3, 1)
+	if f.Lines[3].Marks != 0 {
+		t.Errorf("expected the divider not marked")
+	}
+
+	g := f.Filter(func(l Line) bool { return true })
+	if len(g.Lines) != 7 || g.Lines[0].Context {
+		t.Fatalf("expected no dividers and no context in a plain filter")
 	}
 }
@@ -0,0 +1,289 @@
+package tbuf
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// GCP cloud logging: {"severity", "timestamp", "jsonPayload"|"textPayload", "resource": {"labels"}}
+func isGCP(tags map[string]json.RawMessage) bool {
+	if _, ok := tags["logName"]; ok {
+		return true
+	}
+	if _, ok := tags["severity"]; !ok {
+		return false
+	}
+	for _, k := range []string{"jsonPayload", "textPayload", "protoPayload"} {
+		if _, ok := tags[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func parseGCP(out *Line) {
+	out.Format = "gcp"
+	if j, exists := out.Tags["severity"]; exists {
+		if l, ok := parseLevel(j); ok {
+			if l != "DEFAULT" {
+				out.Level = l
+			}
+			delete(out.Tags, "severity")
+		}
+	}
+	if t, ok := ParseTime(out.Tags["timestamp"]); ok {
+		out.Time = t
+		delete(out.Tags, "timestamp")
+	}
+	if j, exists := out.Tags["textPayload"]; exists {
+		out.Short = unmarshalOrString(j)
+		delete(out.Tags, "textPayload")
+	}
+	if j, exists := out.Tags["jsonPayload"]; exists {
+		payload, keys, err := unmarshalObject(j)
+		if err == nil {
+			delete(out.Tags, "jsonPayload")
+			for _, k := range []string{"message", "msg"} {
+				if m, ok := payload[k]; ok {
+					out.Short = unmarshalOrString(m)
+					delete(payload, k)
+					break
+				}
+			}
+			for _, k := range keys {
+				v, ok := payload[k]
+				if !ok {
+					continue
+				}
+				if _, exists := out.Tags[k]; exists {
+					k = "jsonPayload." + k
+				}
+				out.setTag(k, v)
+			}
+		}
+	}
+	for _, k := range []string{"resource", "labels"} {
+		if j, exists := out.Tags[k]; exists && len(j) > 0 && j[0] == '{' {
+			delete(out.Tags, k)
+			flattenTags(out, k, j)
+		}
+	}
+}
+
+// CloudWatch: {"@timestamp", "@message"}, where the message can be json itself
+func isCloudWatch(tags map[string]json.RawMessage) bool {
+	_, ok := tags["@message"]
+	return ok
+}
+
+func parseCloudWatch(out *Line, log func(...interface{})) {
+	msg := unmarshalOrString(out.Tags["@message"])
+	delete(out.Tags, "@message")
+	outer := out.Tags
+	in := ParseLine(msg, log)
+	out.Short, out.Time, out.Level, out.Source = in.Short, in.Time, in.Level, in.Source
+	if out.Short == "" && in.Tags == nil {
+		out.Short = msg
+	}
+	if in.Tags != nil {
+		keys := out.Keys
+		out.Tags, out.Keys = in.Tags, in.Keys
+		for _, k := range keys {
+			if v, ok := outer[k]; ok && k != "@timestamp" {
+				out.setTag(k, v)
+			}
+		}
+	}
+	if out.Time.IsZero() {
+		if t, ok := ParseTime(outer["@timestamp"]); ok {
+			out.Time = t
+		}
+	}
+	delete(out.Tags, "@timestamp")
+	out.Format = "cloudwatch"
+}
+
+// OpenTelemetry log record: {"timeUnixNano", "severityText", "severityNumber", "body", "attributes"}
+func isOTLP(tags map[string]json.RawMessage) bool {
+	for _, k := range []string{"timeUnixNano", "observedTimeUnixNano", "severityNumber"} {
+		if _, ok := tags[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenTelemetry export: {"resourceLogs": [{"resource", "scopeLogs": [{"scope", "logRecords": [...]}]}]}
+func isOTLPBatch(tags map[string]json.RawMessage) bool {
+	_, ok := tags["resourceLogs"]
+	return ok
+}
+
+type otlpKV struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+type otlpBatch struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []otlpKV `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			Scope struct {
+				Name string `json:"name"`
+			} `json:"scope"`
+			LogRecords []json.RawMessage `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+// expand a batch into one Line per record
+func parseOTLPBatch(s string, log func(...interface{})) (out []Line) {
+	var b otlpBatch
+	err := json.Unmarshal([]byte(s), &b)
+	if err != nil {
+		log("can't unmarshal otlp: %v", err)
+		return nil
+	}
+	for _, rl := range b.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			for _, rec := range sl.LogRecords {
+				l := Line{Str: string(rec)}
+				l.Tags, l.Keys, err = unmarshalObject(rec)
+				if err != nil || l.Tags == nil { // null, or not an object
+					continue
+				}
+				parseOTLP(&l, rl.Resource.Attributes)
+				decodeTags(&l)
+				if sl.Scope.Name != "" {
+					j, _ := json.Marshal(sl.Scope.Name)
+					l.setTag("scope", j)
+				}
+				out = append(out, l)
+			}
+		}
+	}
+	return out
+}
+
+func parseOTLP(out *Line, resource []otlpKV) {
+	out.Format = "otlp"
+	for _, k := range []string{"timeUnixNano", "observedTimeUnixNano"} {
+		if t, ok := ParseTime(out.Tags[k]); ok && t.UnixNano() != 0 {
+			out.Time = t
+			break
+		}
+	}
+	delete(out.Tags, "timeUnixNano")
+	delete(out.Tags, "observedTimeUnixNano")
+
+	if j, exists := out.Tags["severityText"]; exists {
+		if l, ok := parseLevel(j); ok {
+			out.Level = l
+			delete(out.Tags, "severityText")
+		}
+	}
+	if j, exists := out.Tags["severityNumber"]; exists {
+		var n int
+		if json.Unmarshal(j, &n) == nil {
+			if out.Level == "" && n > 0 && n <= 24 {
+				out.Level = []string{"trace", "debug", "info", "warn", "error", "fatal"}[(n-1)/4]
+			}
+			delete(out.Tags, "severityNumber")
+		}
+	}
+
+	if j, exists := out.Tags["body"]; exists {
+		out.Short = unmarshalOrString(otlpValue(j))
+		delete(out.Tags, "body")
+	}
+	if j, exists := out.Tags["attributes"]; exists {
+		var attrs []otlpKV
+		if json.Unmarshal(j, &attrs) == nil {
+			delete(out.Tags, "attributes")
+			for _, kv := range attrs {
+				out.setTag(kv.Key, otlpValue(kv.Value))
+			}
+		}
+	}
+	if j, exists := out.Tags["resource"]; exists {
+		var res struct {
+			Attributes []otlpKV `json:"attributes"`
+		}
+		if json.Unmarshal(j, &res) == nil {
+			delete(out.Tags, "resource")
+			resource = append(resource, res.Attributes...)
+		}
+	}
+	for _, kv := range resource {
+		v := otlpValue(kv.Value)
+		out.setTag("resource."+kv.Key, v)
+		if kv.Key == "service.name" {
+			out.Source = unmarshalOrString(v)
+		}
+	}
+}
+
+// convert an OTLP AnyValue to plain json
+func otlpValue(j json.RawMessage) json.RawMessage {
+	var v map[string]json.RawMessage
+	if json.Unmarshal(j, &v) != nil {
+		return j
+	}
+	for k, x := range v {
+		switch k {
+		case "stringValue", "boolValue", "doubleValue", "bytesValue":
+			return x
+		case "intValue": // int64 are strings in json
+			if _, err := strconv.ParseInt(unmarshalOrString(x), 10, 64); err == nil {
+				return json.RawMessage(unmarshalOrString(x))
+			}
+			return x
+		case "arrayValue":
+			var arr struct {
+				Values []json.RawMessage `json:"values"`
+			}
+			if json.Unmarshal(x, &arr) != nil {
+				return x
+			}
+			out := []json.RawMessage{}
+			for _, e := range arr.Values {
+				out = append(out, otlpValue(e))
+			}
+			j, _ := json.Marshal(out)
+			return j
+		case "kvlistValue":
+			var kvs struct {
+				Values []otlpKV `json:"values"`
+			}
+			if json.Unmarshal(x, &kvs) != nil {
+				return x
+			}
+			out := map[string]json.RawMessage{}
+			for _, kv := range kvs.Values {
+				out[kv.Key] = otlpValue(kv.Value)
+			}
+			j, _ := json.Marshal(out)
+			return j
+		}
+	}
+	return j
+}
+
+// add the fields of a json object as tags, with dotted names
+func flattenTags(out *Line, prefix string, j json.RawMessage) {
+	if len(j) == 0 || j[0] != '{' {
+		out.setTag(prefix, j)
+		return
+	}
+	obj, keys, err := unmarshalObject(j)
+	if err != nil {
+		out.setTag(prefix, j)
+		return
+	}
+	for _, k := range keys {
+		flattenTags(out, fmt.Sprintf("%s.%s", prefix, k), obj[k])
+	}
+}
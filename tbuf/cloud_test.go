@@ -0,0 +1,65 @@
+package tbuf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGCP(t *testing.T) {
+	l := ParseLine(`{"severity":"WARNING","timestamp":"2023-11-14T22:13:20Z","jsonPayload":{"message":"slow","ms":300},"resource":{"type":"k8s_container","labels":{"pod_name":"web-1"}},"logName":"x"}`, t.Log)
+	if l.Format != "gcp" || l.Level != "warn" || l.Short != "slow" {
+		t.Fatalf("bad gcp: %+v", l)
+	}
+	if !l.Time.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("bad time: %v", l.Time)
+	}
+	if string(l.Tags["ms"]) != "300" || string(l.Tags["resource.labels.pod_name"]) != `"web-1"` {
+		t.Errorf("bad tags: %v", l.Tags)
+	}
+}
+
+func TestCloudWatch(t *testing.T) {
+	l := ParseLine(`{"@timestamp":1700000000000,"@logStream":"s1","@message":"{\"level\":\"error\",\"msg\":\"boom\",\"id\":7}"}`, t.Log)
+	if l.Format != "cloudwatch" || l.Level != "error" || l.Short != "boom" {
+		t.Fatalf("bad cloudwatch: %+v", l)
+	}
+	if !l.Time.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("bad time: %v", l.Time)
+	}
+	if string(l.Tags["id"]) != "7" || string(l.Tags["@logStream"]) != `"s1"` {
+		t.Errorf("bad tags: %v", l.Tags)
+	}
+
+	l = ParseLine(`{"@timestamp":"2023-11-14 22:13:20.000","@message":"plain"}`, t.Log)
+	if l.Short != "plain" || l.Time.IsZero() {
+		t.Errorf("bad plain cloudwatch: %+v", l)
+	}
+}
+
+func TestOTLP(t *testing.T) {
+	batch := `{"resourceLogs":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"api"}}]},"scopeLogs":[{"scope":{"name":"lib"},"logRecords":[` +
+		`{"timeUnixNano":"1700000000000000000","severityNumber":17,"body":{"stringValue":"one"},"attributes":[{"key":"n","value":{"intValue":"3"}}]},` +
+		`{"timeUnixNano":"1700000001000000000","severityText":"INFO","body":{"stringValue":"two"}}]}]}]}`
+	lines := ParseLines(batch, t.Log)
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	l := lines[0]
+	if l.Format != "otlp" || l.Level != "error" || l.Short != "one" || l.Source != "api" {
+		t.Fatalf("bad otlp: %+v", l)
+	}
+	if !l.Time.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("bad time: %v", l.Time)
+	}
+	if string(l.Tags["n"]) != "3" || string(l.Tags["scope"]) != `"lib"` || string(l.Tags["resource.service.name"]) != `"api"` {
+		t.Errorf("bad tags: %v", l.Tags)
+	}
+	if lines[1].Level != "info" || lines[1].Short != "two" {
+		t.Errorf("bad second line: %+v", lines[1])
+	}
+
+	lines = ParseLines(`{"resourceLogs":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"api"}}]},"scopeLogs":[{"scope":{"name":"lib"},"logRecords":[null,{"body":{"stringValue":"three"}}]}]}]}`, t.Log)
+	if len(lines) != 1 || lines[0].Short != "three" {
+		t.Errorf("expected the null record skipped, got %+v", lines)
+	}
+}
@@ -44,9 +44,10 @@ func (this *Cursor) Up(f func(s Line) bool) (Line, bool) {
 	p := this.Cur
 	for {
 		p--
-		if p <= 0 {
+		if p <= 0 { // stop at the top, ok only if it matches
 			this.Cur = 0
-			return this.Buffer.At(0), true
+			l := this.Buffer.At(0)
+			return l, f == nil || f(l)
 		}
 		if f == nil || f(this.Buffer.At(p)) {
 			this.Cur = p
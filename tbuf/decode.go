@@ -0,0 +1,80 @@
+package tbuf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var reBase64 = regexp.MustCompile(`^[A-Za-z0-9+/_-]{8,}={0,2}$`)
+var reQuery = regexp.MustCompile(`^[\w.\-\[\]%]+=[^&\s]*(&[\w.\-\[\]%]+=[^&\s]*)+$`)
+
+// replace string values containing json, base64 json or url encoded queries
+// with the decoded value, and keep track of the encoding in Decoded
+func decodeTags(out *Line) {
+	for k, v := range out.Tags {
+		if len(v) < 3 || v[0] != '"' {
+			continue
+		}
+		var s string
+		if json.Unmarshal(v, &s) != nil {
+			continue
+		}
+		if j, as, ok := decodeValue(s); ok {
+			out.Tags[k] = j
+			if out.Decoded == nil {
+				out.Decoded = map[string]string{}
+			}
+			out.Decoded[k] = as
+		}
+	}
+}
+
+func decodeValue(s string) (json.RawMessage, string, bool) {
+	s = strings.TrimSpace(s)
+	if j, ok := jsonContainer(s); ok {
+		return j, "json", true
+	}
+	if reBase64.MatchString(s) {
+		for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+			b, err := enc.DecodeString(s)
+			if err != nil {
+				continue
+			}
+			if j, ok := jsonContainer(strings.TrimSpace(string(b))); ok {
+				return j, "base64", true
+			}
+		}
+	}
+	if reQuery.MatchString(s) {
+		q, err := url.ParseQuery(s)
+		if err == nil {
+			out := map[string]interface{}{}
+			for k, vs := range q {
+				if len(vs) == 1 {
+					out[k] = vs[0]
+				} else {
+					out[k] = vs
+				}
+			}
+			j, _ := json.Marshal(out)
+			return j, "query", true
+		}
+	}
+	return nil, "", false
+}
+
+// a json object or array
+func jsonContainer(s string) (json.RawMessage, bool) {
+	if len(s) < 2 || (s[0] != '{' && s[0] != '[') {
+		return nil, false
+	}
+	b := &bytes.Buffer{}
+	if json.Compact(b, []byte(s)) != nil {
+		return nil, false
+	}
+	return b.Bytes(), true
+}
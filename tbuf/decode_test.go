@@ -0,0 +1,32 @@
+package tbuf
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	b64 := base64.StdEncoding.EncodeToString([]byte(`{"id":2}`))
+	s := `{"msg":"x","payload":"{\"id\":1, \"a\":[1]}","enc":"` + b64 + `","q":"a=1&b=x%20y&b=z","plain":"hello world","n":"12345678"}`
+	l := ParseLine(s, t.Log)
+	for tag, exp := range map[string]string{
+		"payload": `{"id":1,"a":[1]}`,
+		"enc":     `{"id":2}`,
+		"q":       `{"a":"1","b":["x y","z"]}`,
+		"plain":   `"hello world"`,
+		"n":       `"12345678"`,
+	} {
+		if string(l.Tags[tag]) != exp {
+			t.Errorf("%s: expected %s, got %s", tag, exp, l.Tags[tag])
+		}
+	}
+	if l.Decoded["payload"] != "json" || l.Decoded["enc"] != "base64" || l.Decoded["q"] != "query" {
+		t.Errorf("bad decoded: %v", l.Decoded)
+	}
+	if _, ok := l.Decoded["plain"]; ok {
+		t.Errorf("plain should not be decoded")
+	}
+	if l.Str != s {
+		t.Errorf("original changed")
+	}
+}
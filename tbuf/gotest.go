@@ -0,0 +1,134 @@
+package tbuf
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// events of `go test -json` (test2json)
+func isGoTest(tags map[string]json.RawMessage) bool {
+	_, action := tags["Action"]
+	_, pkg := tags["Package"]
+	return action && pkg
+}
+
+func parseGoTest(out *Line) {
+	out.Format = "gotest"
+	if t, ok := ParseTime(out.Tags["Time"]); ok {
+		out.Time = t
+	}
+	out.Short = unmarshalOrString(out.Tags["Output"])
+}
+
+type testEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// GoTest groups the events of `go test -json` into one Line per test
+type GoTest struct {
+	running map[string]*testRun
+	order   []string
+}
+
+type testRun struct {
+	testEvent
+	output strings.Builder
+}
+
+// Feed returns the lines to show: tests when completed, any other line as is
+func (this *GoTest) Feed(lines []Line) (out []Line) {
+	for _, l := range lines {
+		if l.Format != "gotest" {
+			out = append(out, l)
+			continue
+		}
+		var ev testEvent
+		if json.Unmarshal([]byte(l.Str), &ev) != nil {
+			out = append(out, l)
+			continue
+		}
+		if this.running == nil {
+			this.running = map[string]*testRun{}
+		}
+		key := ev.Package + " " + ev.Test
+		run := this.running[key]
+		if run == nil {
+			run = &testRun{testEvent: ev}
+			this.running[key] = run
+			this.order = append(this.order, key)
+		}
+		switch ev.Action {
+		case "output":
+			run.output.WriteString(ev.Output)
+		case "pass", "fail", "skip":
+			run.Action = ev.Action
+			run.Time = ev.Time
+			run.Elapsed = ev.Elapsed
+			out = append(out, run.line())
+			this.remove(key)
+		}
+	}
+	return out
+}
+
+// Flush returns the tests which never completed (e.g. after a panic or a timeout)
+func (this *GoTest) Flush() (out []Line) {
+	for _, key := range this.order {
+		run := this.running[key]
+		run.Action = "incomplete"
+		out = append(out, run.line())
+	}
+	this.running = nil
+	this.order = nil
+	return out
+}
+
+func (this *GoTest) remove(key string) {
+	delete(this.running, key)
+	for i, k := range this.order {
+		if k == key {
+			this.order = append(this.order[:i], this.order[i+1:]...)
+			return
+		}
+	}
+}
+
+func (this *testRun) line() Line {
+	name := this.Test
+	if name == "" {
+		name = "package"
+	}
+	out := Line{
+		Str:    strings.TrimRight(this.output.String(), "\n"),
+		Short:  fmt.Sprintf("%s %s (%.2fs)", strings.ToUpper(this.Action), name, this.Elapsed),
+		Time:   this.Time,
+		Source: this.Package,
+		Format: "gotest",
+	}
+	switch this.Action {
+	case "pass":
+		out.Level = "info"
+	case "skip":
+		out.Level = "notice"
+	case "fail":
+		out.Level = "error"
+	default:
+		out.Level = "warn"
+	}
+	j, _ := json.Marshal(this.Package)
+	out.setTag("Package", j)
+	if this.Test != "" {
+		j, _ = json.Marshal(this.Test)
+		out.setTag("Test", j)
+	}
+	j, _ = json.Marshal(this.Elapsed)
+	out.setTag("Elapsed", j)
+	return out
+}
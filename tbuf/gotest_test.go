@@ -0,0 +1,39 @@
+package tbuf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGoTest(t *testing.T) {
+	g := &GoTest{}
+	var out []Line
+	for _, s := range []string{
+		`{"Time":"2023-11-14T22:13:20Z","Action":"run","Package":"x/y","Test":"TestA"}`,
+		`{"Time":"2023-11-14T22:13:20Z","Action":"output","Package":"x/y","Test":"TestA","Output":"=== RUN   TestA\n"}`,
+		`{"Time":"2023-11-14T22:13:20Z","Action":"run","Package":"x/y","Test":"TestB"}`,
+		`{"Time":"2023-11-14T22:13:20Z","Action":"output","Package":"x/y","Test":"TestA","Output":"    a_test.go:3: boom\n"}`,
+		`{"Time":"2023-11-14T22:13:21Z","Action":"fail","Package":"x/y","Test":"TestA","Elapsed":0.5}`,
+		`not json`,
+		`{"Time":"2023-11-14T22:13:21Z","Action":"output","Package":"x/y","Test":"TestB","Output":"=== RUN   TestB\n"}`,
+	} {
+		out = append(out, g.Feed(ParseLines(s, t.Log))...)
+	}
+	out = append(out, g.Flush()...)
+	if len(out) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %+v", len(out), out)
+	}
+	a := out[0]
+	if a.Level != "error" || a.Source != "x/y" || !strings.HasPrefix(a.Short, "FAIL TestA") {
+		t.Errorf("bad test line: %+v", a)
+	}
+	if a.Str != "=== RUN   TestA\n    a_test.go:3: boom" {
+		t.Errorf("bad output: %q", a.Str)
+	}
+	if out[1].Str != "not json" {
+		t.Errorf("expected other lines to pass through: %+v", out[1])
+	}
+	if out[2].Level != "warn" || !strings.HasPrefix(out[2].Short, "INCOMPLETE TestB") {
+		t.Errorf("bad incomplete test: %+v", out[2])
+	}
+}
@@ -0,0 +1,57 @@
+package tbuf
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// output of `journalctl -o json`
+func isJournal(tags map[string]json.RawMessage) bool {
+	_, ok := tags["__REALTIME_TIMESTAMP"]
+	return ok
+}
+
+func parseJournal(out *Line) {
+	out.Format = "journal"
+
+	if j, exists := out.Tags["MESSAGE"]; exists {
+		var b []byte
+		if j[0] == '[' && json.Unmarshal(j, &b) == nil { // non utf8 messages are arrays of bytes
+			out.Short = string(b)
+		} else {
+			out.Short = unmarshalOrString(j)
+		}
+		delete(out.Tags, "MESSAGE")
+	}
+
+	if t, ok := ParseTime(out.Tags["__REALTIME_TIMESTAMP"]); ok {
+		out.Time = t
+		delete(out.Tags, "__REALTIME_TIMESTAMP")
+	}
+
+	if j, exists := out.Tags["PRIORITY"]; exists {
+		n, err := strconv.Atoi(unmarshalOrString(j))
+		if l, ok := SyslogLevel(n); err == nil && ok {
+			out.Level = l
+			delete(out.Tags, "PRIORITY")
+		}
+	}
+
+	for _, k := range []string{"_SYSTEMD_UNIT", "SYSLOG_IDENTIFIER", "_COMM"} {
+		if j, exists := out.Tags[k]; exists {
+			out.Source = unmarshalOrString(j)
+			break
+		}
+	}
+	for _, k := range []string{"_PID", "SYSLOG_PID"} {
+		if j, exists := out.Tags[k]; exists {
+			out.Source += "[" + unmarshalOrString(j) + "]"
+			break
+		}
+	}
+}
+
+// trusted fields (added by journald) and address fields
+func (this Line) IsTrusted(tag string) bool {
+	return this.Format == "journal" && len(tag) > 0 && tag[0] == '_'
+}
@@ -0,0 +1,28 @@
+package tbuf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	l := ParseLine(`{"__CURSOR":"s=1","__REALTIME_TIMESTAMP":"1700000000123456","PRIORITY":"3","_SYSTEMD_UNIT":"nginx.service","_PID":"42","SYSLOG_IDENTIFIER":"nginx","MESSAGE":[104,105,255]}`, t.Log)
+	if l.Format != "journal" {
+		t.Fatalf("not detected: %+v", l)
+	}
+	if l.Short != "hi\xff" {
+		t.Errorf("bad message: %q", l.Short)
+	}
+	if !l.Time.Equal(time.Unix(1700000000, 123456000)) {
+		t.Errorf("bad time: %v", l.Time)
+	}
+	if l.Level != "error" {
+		t.Errorf("bad level: %q", l.Level)
+	}
+	if l.Source != "nginx.service[42]" {
+		t.Errorf("bad source: %q", l.Source)
+	}
+	if !l.IsTrusted("_PID") || !l.IsTrusted("__CURSOR") || l.IsTrusted("SYSLOG_IDENTIFIER") {
+		t.Errorf("bad trusted fields")
+	}
+}
@@ -0,0 +1,134 @@
+package tbuf
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// canonical levels, from the least to the most severe
+var LevelOrder = []string{"trace", "debug", "info", "notice", "warn", "error", "fatal"}
+
+// maps a lowercase level to its canonical name
+var LevelAliases = map[string]string{
+	"trace":         "trace",
+	"trc":           "trace",
+	"t":             "trace",
+	"verbose":       "trace",
+	"debug":         "debug",
+	"dbg":           "debug",
+	"d":             "debug",
+	"info":          "info",
+	"inf":           "info",
+	"i":             "info",
+	"information":   "info",
+	"informational": "info",
+	"notice":        "notice",
+	"ntc":           "notice",
+	"n":             "notice",
+	"warn":          "warn",
+	"warning":       "warn",
+	"wrn":           "warn",
+	"w":             "warn",
+	"error":         "error",
+	"err":           "error",
+	"e":             "error",
+	"fatal":         "fatal",
+	"ftl":           "fatal",
+	"f":             "fatal",
+	"crit":          "fatal",
+	"critical":      "fatal",
+	"alert":         "fatal",
+	"emerg":         "fatal",
+	"emergency":     "fatal",
+	"panic":         "fatal",
+}
+
+var syslogLevels = []string{"fatal", "fatal", "fatal", "error", "warn", "notice", "info", "debug"}
+
+// SetLevelOrder replaces the canonical levels, from the least to the most severe
+func SetLevelOrder(levels []string) {
+	LevelOrder = nil
+	for _, l := range levels {
+		l = strings.ToLower(strings.TrimSpace(l))
+		LevelOrder = append(LevelOrder, l)
+		LevelAliases[l] = l
+	}
+}
+
+// MapLevel makes alias a synonym of the given level
+func MapLevel(alias, level string) {
+	LevelAliases[strings.ToLower(alias)] = strings.ToLower(level)
+}
+
+// SyslogLevel maps syslog severities (0 emerg ... 7 debug)
+func SyslogLevel(n int) (string, bool) {
+	if n < 0 || n >= len(syslogLevels) {
+		return "", false
+	}
+	return syslogLevels[n], true
+}
+
+// NumericLevel maps syslog severities (0-7) and pino/bunyan levels (10-60)
+func NumericLevel(n int) (string, bool) {
+	switch {
+	case n < 0:
+		return "", false
+	case n < 8:
+		return SyslogLevel(n)
+	case n < 10:
+		return "", false
+	case n < 20:
+		return "trace", true
+	case n < 30:
+		return "debug", true
+	case n < 40:
+		return "info", true
+	case n < 50:
+		return "warn", true
+	case n < 60:
+		return "error", true
+	default:
+		return "fatal", true
+	}
+}
+
+// NormalizeLevel returns the canonical name of a level, or the level itself if unknown
+func NormalizeLevel(s string) string {
+	if l, ok := LevelAliases[strings.ToLower(s)]; ok {
+		return l
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if l, ok := NumericLevel(n); ok {
+			return l
+		}
+	}
+	return s
+}
+
+// parse a json level, either as a string or a number
+func parseLevel(j json.RawMessage) (string, bool) {
+	var s string
+	if json.Unmarshal(j, &s) == nil {
+		return NormalizeLevel(s), true
+	}
+	var n float64
+	if json.Unmarshal(j, &n) == nil {
+		return NumericLevel(int(n))
+	}
+	return "", false
+}
+
+// Severity of a level, or -1 if unknown
+func Severity(level string) int {
+	for i, l := range LevelOrder {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+func (this Line) Severity() int {
+	return Severity(this.Level)
+}
@@ -0,0 +1,51 @@
+package tbuf
+
+import "testing"
+
+func TestLevels(t *testing.T) {
+	for in, exp := range map[string]string{
+		`{"level":30}`:        "info",
+		`{"level":50}`:        "error",
+		`{"level":60}`:        "fatal",
+		`{"level":3}`:         "error",
+		`{"level":"4"}`:       "warn",
+		`{"level":"WARNING"}`: "warn",
+		`{"level":"E"}`:       "error",
+		`{"level":"crit"}`:    "fatal",
+		`{"level":"trace"}`:   "trace",
+		`{"level":"custom"}`:  "custom",
+	} {
+		l := ParseLine(in, t.Log)
+		if l.Level != exp {
+			t.Errorf("%s: expected %q, got %q", in, exp, l.Level)
+		}
+		if _, exists := l.Tags["level"]; exists {
+			t.Errorf("%s: level still in tags", in)
+		}
+	}
+
+	if Severity("warn") <= Severity("info") || Severity("error") <= Severity("warn") {
+		t.Errorf("bad ordering: %v", LevelOrder)
+	}
+	if Severity("custom") != -1 {
+		t.Errorf("unknown level should have no severity")
+	}
+}
+
+func TestLevelsCustom(t *testing.T) {
+	order, aliases := LevelOrder, map[string]string{}
+	for k, v := range LevelAliases {
+		aliases[k] = v
+	}
+	defer func() { LevelOrder, LevelAliases = order, aliases }()
+
+	SetLevelOrder([]string{"debug", "info", "warn", "error", "critical", "fatal"})
+	MapLevel("oops", "critical")
+	l := ParseLine(`{"level":"OOPS"}`, t.Log)
+	if l.Level != "critical" {
+		t.Fatalf("expected critical, got %q", l.Level)
+	}
+	if l.Severity() <= Severity("error") {
+		t.Fatalf("critical should be above error")
+	}
+}
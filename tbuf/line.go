@@ -1,10 +1,14 @@
 package tbuf
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
 	"sort"
-	"strconv"
 	"time"
+
+	"github.com/ohait/jl/util"
 )
 
 // change for different pattern
@@ -24,12 +28,20 @@ var alt = Schema{
 }
 
 type Line struct {
-	Str   string
-	Short string
-	Tags  map[string]json.RawMessage
-	Time  time.Time
-	Level string
-	Mark  bool
+	Str    string
+	Short  string
+	Tags   map[string]json.RawMessage
+	Keys   []string // order of the tags in the source
+	ANSI   string   // the original line, or the message, if it had escape sequences (Str and Short have them stripped)
+	Time   time.Time
+	Level  string
+	Source string // where the line comes from (e.g. unit[pid])
+	Format string // the detected format, if not the default schema
+	// tags which have been decoded (json, base64 or query), Str keeps the original
+	Decoded map[string]string
+	Marks   Marks // the mark sets the line is in
+	Context bool  // around the matching lines of a filter, not matching itself
+	Divider bool  // between the groups of a filter with context, not a real line
 }
 
 func (this Line) SortedTags() (out []string) {
@@ -40,6 +52,73 @@ func (this Line) SortedTags() (out []string) {
 	return
 }
 
+// OrderedTags returns the tags in the order they appear in the source
+func (this Line) OrderedTags() (out []string) {
+	seen := map[string]bool{}
+	for _, k := range this.Keys {
+		if _, ok := this.Tags[k]; ok && !seen[k] {
+			seen[k] = true
+			out = append(out, k)
+		}
+	}
+	for _, k := range this.SortedTags() {
+		if !seen[k] {
+			out = append(out, k)
+		}
+	}
+	return
+}
+
+// set a tag, keeping track of the order
+func (this *Line) setTag(k string, v json.RawMessage) {
+	if this.Tags == nil {
+		this.Tags = map[string]json.RawMessage{}
+	}
+	if _, exists := this.Tags[k]; !exists {
+		this.Keys = append(this.Keys, k)
+	}
+	this.Tags[k] = v
+}
+
+// unmarshal a json object, returning also the order of the keys
+func unmarshalObject(b []byte) (map[string]json.RawMessage, []string, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	t, err := d.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+	if t != json.Delim('{') {
+		return nil, nil, fmt.Errorf("expected an object, got %v", t)
+	}
+	out := map[string]json.RawMessage{}
+	keys := []string{}
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		k, _ := t.(string)
+		var v json.RawMessage
+		err = d.Decode(&v)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, exists := out[k]; !exists {
+			keys = append(keys, k)
+		}
+		out[k] = v
+	}
+	if t, err := d.Token(); err != nil {
+		return nil, nil, err
+	} else if t != json.Delim('}') {
+		return nil, nil, fmt.Errorf("expected the end of the object, got %v", t)
+	}
+	if _, err := d.Token(); err != io.EOF { // also a stray } or ], which More() doesn't see
+		return nil, nil, fmt.Errorf("trailing data after the object")
+	}
+	return out, keys, nil
+}
+
 func unmarshalOrString(in []byte) string {
 	var s string
 	err := json.Unmarshal(in, &s)
@@ -51,63 +130,112 @@ func unmarshalOrString(in []byte) string {
 }
 
 func (this *Buffer) Append(s string, log func(...interface{})) {
+	this.Add(ParseLines(s, log)...)
+}
+
+// Add appends parsed lines, following the tail if there
+func (this *Buffer) Add(lines ...Line) {
+	if len(lines) == 0 {
+		return
+	}
 	this.m.Lock()
 	defer this.m.Unlock()
 	if this.Pos == len(this.Lines) {
-		this.Pos++
+		this.Pos += len(lines)
+	}
+	for _, l := range lines {
+		this.push(l)
 	}
-	l := ParseLine(s, log)
-	this.Lines = append(this.Lines, l)
 	this.Last = time.Now()
 }
+
 func (this *Buffer) AppendLine(l Line) {
 	this.m.Lock()
 	defer this.m.Unlock()
-	this.Lines = append(this.Lines, l)
+	this.push(l)
+}
+
+// ParseLines parses a record, which can contain more lines (e.g. OTLP batches)
+func ParseLines(s string, log func(...interface{})) []Line {
+	l := ParseLine(s, log)
+	if l.Format == "otlp" && isOTLPBatch(l.Tags) {
+		if out := parseOTLPBatch(s, log); len(out) > 0 {
+			return out
+		}
+	}
+	return []Line{l}
 }
 
 func ParseLine(s string, log func(...interface{})) (out Line) {
+	if util.HasANSI(s) {
+		out.ANSI = s
+		s = util.StripANSI(s)
+	}
 	out.Str = s
 	if len(s) < 2 {
 		return
 	}
-	if s[0] == '{' {
-		err := json.Unmarshal([]byte(s), &out.Tags)
-		if err != nil {
-			log("can't unmarshal: %v", err)
-			return
-		}
+	if s[0] != '{' {
+		parsePlain(&out)
+		return
+	}
+	var err error
+	out.Tags, out.Keys, err = unmarshalObject([]byte(s))
+	if err != nil {
+		log("can't unmarshal: %v", err)
+		parsePlain(&out)
+		return
+	}
 
-		// if message, then use it as main message
-		if j, exists := out.Tags[def.Message]; exists {
-			delete(out.Tags, def.Message)
-			out.Short = unmarshalOrString(j)
-		} else if j, exists := out.Tags[alt.Message]; exists {
-			delete(out.Tags, def.Message)
-			out.Short = unmarshalOrString(j)
-		}
+	switch {
+	case isJournal(out.Tags):
+		parseJournal(&out)
+	case isGCP(out.Tags):
+		parseGCP(&out)
+	case isCloudWatch(out.Tags):
+		parseCloudWatch(&out, log)
+	case isOTLPBatch(out.Tags):
+		out.Format = "otlp"
+		out.Short = "OTLP batch"
+	case isOTLP(out.Tags):
+		parseOTLP(&out, nil)
+	case isGoTest(out.Tags):
+		parseGoTest(&out)
+	default:
+		parseSchema(&out)
+	}
+	decodeTags(&out)
+	if util.HasANSI(out.Short) { // from escaped json strings, keep them to render the colors
+		out.ANSI = out.Short
+		out.Short = util.StripANSI(out.Short)
+	}
+	return
+}
+
+// extract message, time and level using the default schema
+func parseSchema(out *Line) {
+	// if message, then use it as main message
+	if j, exists := out.Tags[def.Message]; exists {
+		delete(out.Tags, def.Message)
+		out.Short = unmarshalOrString(j)
+	} else if j, exists := out.Tags[alt.Message]; exists {
+		delete(out.Tags, alt.Message)
+		out.Short = unmarshalOrString(j)
+	}
 
-		// check for "time"
-		if j, exists := out.Tags[def.Time]; exists {
-			err := json.Unmarshal(j, &out.Time)
-			if err == nil {
-				delete(out.Tags, def.Time)
-			} else {
-				epoch, err := strconv.ParseInt(string(j), 10, 64)
-				if err == nil {
-					out.Time = time.Unix(epoch, 0)
-					delete(out.Tags, def.Time)
-				}
-			}
+	// check for "time"
+	if j, exists := out.Tags[def.Time]; exists {
+		if t, ok := ParseTime(j); ok {
+			out.Time = t
+			delete(out.Tags, def.Time)
 		}
+	}
 
-		// check for "time"
-		if j, exists := out.Tags[def.Level]; exists {
-			err := json.Unmarshal(j, &out.Level)
-			if err == nil {
-				delete(out.Tags, def.Level)
-			}
+	// check for "level"
+	if j, exists := out.Tags[def.Level]; exists {
+		if l, ok := parseLevel(j); ok {
+			out.Level = l
+			delete(out.Tags, def.Level)
 		}
 	}
-	return
 }
@@ -0,0 +1,29 @@
+package tbuf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrderedTags(t *testing.T) {
+	l := ParseLine(`{"zeta":1,"message":"x","alpha":2,"mid":{"b":1,"a":2},"alpha":3}`, t.Log)
+	if exp := []string{"zeta", "alpha", "mid"}; !reflect.DeepEqual(l.OrderedTags(), exp) {
+		t.Errorf("expected %v, got %v", exp, l.OrderedTags())
+	}
+	if exp := []string{"alpha", "mid", "zeta"}; !reflect.DeepEqual(l.SortedTags(), exp) {
+		t.Errorf("expected %v, got %v", exp, l.SortedTags())
+	}
+	if string(l.Tags["alpha"]) != "3" {
+		t.Errorf("expected the last value, got %s", l.Tags["alpha"])
+	}
+
+	for _, s := range []string{`{"a":1}}`, `{"a":1}]`, `{"a":1`} {
+		if l := ParseLine(s, t.Log); l.Tags != nil {
+			t.Errorf("%s: expected plain text, got %v", s, l.Tags)
+		}
+	}
+	l = ParseLine(`{"a":1} trailing`, t.Log)
+	if l.Tags != nil {
+		t.Errorf("expected invalid json, got %v", l.Tags)
+	}
+}
@@ -0,0 +1,167 @@
+package tbuf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// the names of the mark sets
+const MarkSets = "abcdefgh"
+
+// Marks are the sets a line is in, one bit per set
+type Marks uint8
+
+// MarkSet returns the set with the given name
+func MarkSet(name byte) (Marks, bool) {
+	i := strings.IndexByte(MarkSets, name)
+	if i < 0 {
+		return 0, false
+	}
+	return 1 << uint(i), true
+}
+
+// the names of the sets, e.g. "ac"
+func (this Marks) String() string {
+	out := []byte{}
+	for i := 0; i < len(MarkSets); i++ {
+		if this&(1<<uint(i)) != 0 {
+			out = append(out, MarkSets[i])
+		}
+	}
+	return string(out)
+}
+
+// MarkExpr combines the sets: a & b (both), a | b (either), a - b (a but not b),
+// !a, with parentheses. If it starts with "c =" the result goes in the set c
+type MarkExpr struct {
+	Assign Marks // the set to fill, or 0
+	eval   func(Marks) bool
+	src    string
+}
+
+func (this *MarkExpr) Match(l Line) bool {
+	return this.eval(l.Marks)
+}
+
+func (this *MarkExpr) String() string {
+	return this.src
+}
+
+// ParseMarkExpr parses an expression like "a & !b" or "c = a | b"
+func ParseMarkExpr(s string) (*MarkExpr, error) {
+	p := &markParser{s: s}
+	out := &MarkExpr{src: strings.TrimSpace(s)}
+	if i := strings.IndexByte(s, '='); i >= 0 {
+		name := strings.TrimSpace(s[:i])
+		if len(name) != 1 {
+			return nil, fmt.Errorf("expected a set name before =, got %q", name)
+		}
+		m, ok := MarkSet(name[0])
+		if !ok {
+			return nil, fmt.Errorf("unknown set %q", name)
+		}
+		out.Assign = m
+		p.pos = i + 1
+	}
+	var err error
+	out.eval, err = p.union()
+	if err != nil {
+		return nil, err
+	}
+	if c := p.peek(); c != 0 {
+		return nil, fmt.Errorf("unexpected %q at %d", c, p.pos)
+	}
+	return out, nil
+}
+
+type markParser struct {
+	s   string
+	pos int
+}
+
+// the next char, skipping spaces, or 0 at the end
+func (this *markParser) peek() byte {
+	for this.pos < len(this.s) && this.s[this.pos] == ' ' {
+		this.pos++
+	}
+	if this.pos >= len(this.s) {
+		return 0
+	}
+	return this.s[this.pos]
+}
+
+// union := inter { '|' inter }
+func (this *markParser) union() (func(Marks) bool, error) {
+	left, err := this.inter()
+	if err != nil {
+		return nil, err
+	}
+	for this.peek() == '|' || this.peek() == '+' {
+		this.pos++
+		right, err := this.inter()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(m Marks) bool { return l(m) || right(m) }
+	}
+	return left, nil
+}
+
+// inter := unary { ('&' | '-') unary }
+func (this *markParser) inter() (func(Marks) bool, error) {
+	left, err := this.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := this.peek()
+		if op != '&' && op != '-' {
+			return left, nil
+		}
+		this.pos++
+		right, err := this.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == '&' {
+			left = func(m Marks) bool { return l(m) && right(m) }
+		} else {
+			left = func(m Marks) bool { return l(m) && !right(m) }
+		}
+	}
+}
+
+// unary := '!' unary | '(' union ')' | name
+func (this *markParser) unary() (func(Marks) bool, error) {
+	c := this.peek()
+	switch {
+	case c == '!':
+		this.pos++
+		f, err := this.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(m Marks) bool { return !f(m) }, nil
+	case c == '(':
+		this.pos++
+		f, err := this.union()
+		if err != nil {
+			return nil, err
+		}
+		if this.peek() != ')' {
+			return nil, fmt.Errorf("missing ) at %d", this.pos)
+		}
+		this.pos++
+		return f, nil
+	case c == 0:
+		return nil, fmt.Errorf("expected a set at the end")
+	}
+	set, ok := MarkSet(c)
+	if !ok {
+		return nil, fmt.Errorf("unknown set %q at %d", c, this.pos)
+	}
+	this.pos++
+	return func(m Marks) bool { return m&set != 0 }, nil
+}
@@ -0,0 +1,52 @@
+package tbuf
+
+import (
+	"testing"
+)
+
+func TestMarkExpr(t *testing.T) {
+	a, _ := MarkSet('a')
+	b, _ := MarkSet('b')
+	c, _ := MarkSet('c')
+	if (a | c).String() != "ac" {
+		t.Errorf("bad names: %q", (a | c).String())
+	}
+	for _, x := range []struct {
+		expr  string
+		marks Marks
+		match bool
+	}{
+		{"a", a, true},
+		{"a", b, false},
+		{"a & !b", a, true},
+		{"a & !b", a | b, false},
+		{"a - b", a | b, false},
+		{"a|b", b, true},
+		{"a + b", 0, false},
+		{"!a", 0, true},
+		{"(a | b) & c", b | c, true},
+		{"a | b & c", a, true}, // & binds tighter
+		{"a | b & c", b, false},
+		{"!(a|b)", c, true},
+	} {
+		e, err := ParseMarkExpr(x.expr)
+		if err != nil {
+			t.Errorf("%q: %v", x.expr, err)
+			continue
+		}
+		if got := e.Match(Line{Marks: x.marks}); got != x.match {
+			t.Errorf("%q on %q: expected %v, got %v", x.expr, x.marks, x.match, got)
+		}
+	}
+
+	e, err := ParseMarkExpr("c = a | b")
+	if err != nil || e.Assign != c || !e.Match(Line{Marks: b}) {
+		t.Errorf("bad assignment: %+v %v", e, err)
+	}
+
+	for _, s := range []string{"", "a &", "z", "a b", "(a", "ab = a", "x = a"} {
+		if _, err := ParseMarkExpr(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
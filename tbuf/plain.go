@@ -0,0 +1,50 @@
+package tbuf
+
+import (
+	"regexp"
+	"strings"
+)
+
+// a timestamp at the start of a line, optionally in brackets
+var rePlainTime = regexp.MustCompile(`^\[?(\d{4}[-/]\d\d[-/]\d\d[T ]\d\d:\d\d(:\d\d([.,]\d+)?)?(Z|[+-]\d\d:?\d\d)?|[A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d(\.\d+)?|\d\d/[A-Z][a-z]{2}/\d{4}:\d\d:\d\d:\d\d [+-]\d{4})\]?[ \t]*`)
+
+// a level: uppercase, in brackets or as level=...
+var rePlainLevel = regexp.MustCompile(`(^|[ \t])(\[(?i:(trace|debug|info|notice|warn|warning|error|err|fatal|crit|critical|panic))\]|(TRACE|DEBUG|INFO|NOTICE|WARN|WARNING|ERROR|FATAL|CRIT|CRITICAL|PANIC)|(?i:level)=(\w+)):?([ \t]+|$)`)
+
+// how far from the start a level is looked for
+const plainLevelWithin = 40
+
+// promote a leading timestamp and a level near the start to Time and Level
+func parsePlain(out *Line) {
+	s := out.Str
+	found := false
+	if m := rePlainTime.FindStringSubmatchIndex(s); m != nil {
+		if t, ok := ParseTimeString(s[m[2]:m[3]]); ok {
+			out.Time = t
+			s = s[m[1]:]
+			found = true
+		}
+	}
+
+	head := s
+	if i := strings.IndexByte(head, '\n'); i >= 0 {
+		head = head[:i]
+	}
+	if len(head) > plainLevelWithin {
+		head = head[:plainLevelWithin]
+	}
+	if m := rePlainLevel.FindStringSubmatchIndex(head); m != nil {
+		for i := 6; i <= 10; i += 2 { // bracketed, uppercase or level=
+			if m[i] >= 0 {
+				out.Level = NormalizeLevel(head[m[i]:m[i+1]])
+				break
+			}
+		}
+		s = s[:m[3]] + s[m[1]:] // keep the space before it
+		found = true
+	}
+
+	if found {
+		out.Short = strings.TrimLeft(s, " \t")
+	}
+}
@@ -0,0 +1,52 @@
+package tbuf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlain(t *testing.T) {
+	Location = time.UTC
+	defer func() { Location = time.Local }()
+	for _, c := range []struct {
+		in, short, level string
+		time             string
+	}{
+		{"2024/01/01 10:00:00 ERROR boom", "boom", "error", "2024-01-01T10:00:00Z"},
+		{"2024-01-01T10:00:00.123Z [warn] slow", "slow", "warn", "2024-01-01T10:00:00.123Z"},
+		{"[2024-01-01 10:00:00,500] INFO: started", "started", "info", "2024-01-01T10:00:00.5Z"},
+		{"2024-01-01 10:00:00 [main] DEBUG x.y - hello", "[main] x.y - hello", "debug", "2024-01-01T10:00:00Z"},
+		{"time=x level=warning msg=hi", "time=x msg=hi", "warn", ""},
+		{"ERROR no time", "no time", "error", ""},
+		{"2024-01-01 10:00:00 plain\n\tERROR in the trace", "plain\n\tERROR in the trace", "", "2024-01-01T10:00:00Z"},
+		{"an error occurred", "", "", ""},
+		{"Welcome to the banner", "", "", ""},
+	} {
+		l := ParseLine(c.in, t.Log)
+		if l.Short != c.short || l.Level != c.level {
+			t.Errorf("%q: expected %q/%q, got %q/%q", c.in, c.short, c.level, l.Short, l.Level)
+		}
+		if c.time == "" {
+			if !l.Time.IsZero() {
+				t.Errorf("%q: unexpected time %v", c.in, l.Time)
+			}
+		} else if exp, _ := time.Parse(time.RFC3339Nano, c.time); !l.Time.Equal(exp) {
+			t.Errorf("%q: expected %v, got %v", c.in, exp, l.Time)
+		}
+	}
+}
+
+func TestANSI(t *testing.T) {
+	l := ParseLine("\x1b[31mERROR\x1b[0m boom", t.Log)
+	if l.Str != "ERROR boom" || l.Level != "error" || l.Short != "boom" || l.ANSI == "" {
+		t.Errorf("bad plain ansi: %+v", l)
+	}
+	l = ParseLine("{\"msg\":\"\x1b[1mhi\x1b[0m\",\"level\":\"info\"}", t.Log)
+	if l.Short != "hi" || l.Level != "info" {
+		t.Errorf("bad json ansi: %+v", l)
+	}
+	l = ParseLine(`{"msg":"\u001b[1mhi\u001b[0m"}`, t.Log)
+	if l.Short != "hi" || l.ANSI != "\x1b[1mhi\x1b[0m" {
+		t.Errorf("bad escaped ansi: %+v", l)
+	}
+}
@@ -0,0 +1,262 @@
+package tbuf
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// where a query looks for matches
+type Scope int
+
+const (
+	ScopeRaw     Scope = iota // the whole line, as read
+	ScopeMessage              // the message only
+	ScopeValues               // the message and the values of the tags
+	ScopeKeys                 // the names of the tags
+	ScopeField                // the value of a single tag
+)
+
+var scopePrefixes = map[string]Scope{
+	"r:": ScopeRaw,
+	"m:": ScopeMessage,
+	"v:": ScopeValues,
+	"k:": ScopeKeys,
+}
+
+// a field name, followed by = and the pattern
+var reFieldQuery = regexp.MustCompile(`^([\w.@$-]+)=`)
+
+// how letters case is compared
+type CaseMode int
+
+const (
+	CaseSensitive CaseMode = iota
+	CaseSmart              // insensitive, unless the pattern has upper case letters
+	CaseInsensitive
+)
+
+func (this CaseMode) String() string {
+	switch this {
+	case CaseSmart:
+		return "smart case"
+	case CaseInsensitive:
+		return "ignore case"
+	default:
+		return "match case"
+	}
+}
+
+// the parts of a line, as shown
+type Part int
+
+const (
+	PartMessage Part = iota
+	PartKey
+	PartValue
+	PartRaw // the whole line, pretty printed
+)
+
+// Query is a pattern, with where to look for it
+type Query struct {
+	Scope Scope
+	Field string // for ScopeField
+	Re    *regexp.Regexp
+	raw   *regexp.Regexp // field=... as typed, for the lines without the field
+	src   string
+}
+
+// ParseQuery reads the scope from the prefix (m:, v:, k:, field=, r:), the
+// rest is a regexp, or a literal if it doesn't compile
+func ParseQuery(s string, mode CaseMode) *Query {
+	q := &Query{src: s}
+	for p, scope := range scopePrefixes {
+		if strings.HasPrefix(s, p) {
+			q.Scope = scope
+			s = s[len(p):]
+			break
+		}
+	}
+	if q.Scope == ScopeRaw && !strings.HasPrefix(q.src, "r:") {
+		if m := reFieldQuery.FindStringSubmatch(s); m != nil {
+			q.Scope, q.Field = ScopeField, m[1]
+			q.raw = compileQuery(s, mode)
+			s = s[len(m[0]):]
+		}
+	}
+	q.Re = compileQuery(s, mode)
+	return q
+}
+
+// a regexp, or a literal if it doesn't compile
+func compileQuery(s string, mode CaseMode) *regexp.Regexp {
+	if _, err := regexp.Compile(s); err != nil {
+		s = regexp.QuoteMeta(s)
+	}
+	if mode == CaseInsensitive || mode == CaseSmart && !hasUpper(s) {
+		s = "(?i)" + s
+	}
+	return regexp.MustCompile(s)
+}
+
+// upper case letters, not counting escapes like \S or \W
+func hasUpper(s string) bool {
+	escaped := false
+	for _, r := range s {
+		if !escaped && unicode.IsUpper(r) {
+			return true
+		}
+		escaped = !escaped && r == '\\'
+	}
+	return false
+}
+
+// the query as typed
+func (this *Query) String() string {
+	return this.src
+}
+
+func (this *Query) Match(l Line) bool {
+	if l.Divider {
+		return false
+	}
+	switch this.Scope {
+	case ScopeMessage:
+		return this.Re.MatchString(l.Message())
+	case ScopeValues:
+		if this.Re.MatchString(l.Message()) {
+			return true
+		}
+		for _, v := range l.Tags {
+			if this.Re.MatchString(unmarshalOrString(v)) {
+				return true
+			}
+		}
+		return false
+	case ScopeKeys:
+		for k := range l.Tags {
+			if this.Re.MatchString(k) {
+				return true
+			}
+		}
+		return false
+	case ScopeField:
+		switch this.Field {
+		case "level":
+			return this.Re.MatchString(l.Level)
+		case "message", "msg":
+			if _, ok := l.Tags[this.Field]; !ok {
+				return this.Re.MatchString(l.Message())
+			}
+		}
+		v, ok := l.lookup(this.Field)
+		if !ok { // plain text or logfmt, e.g. status=500
+			return this.raw.MatchString(l.Str)
+		}
+		return this.Re.MatchString(unmarshalOrString(v))
+	default:
+		return this.Re.MatchString(l.Str)
+	}
+}
+
+// Highlight returns what to highlight in a part of the line (the value or
+// key of field), or nil if the query doesn't look there
+func (this *Query) Highlight(part Part, field string) *regexp.Regexp {
+	if this == nil {
+		return nil
+	}
+	if part == PartRaw {
+		if this.raw != nil {
+			return this.raw
+		}
+		return this.Re
+	}
+	switch this.Scope {
+	case ScopeMessage:
+		if part != PartMessage {
+			return nil
+		}
+	case ScopeValues:
+		if part == PartKey {
+			return nil
+		}
+	case ScopeKeys:
+		if part != PartKey {
+			return nil
+		}
+	case ScopeField:
+		if part == PartMessage && this.Field != "message" && this.Field != "msg" {
+			return this.raw // as typed, for the lines without the field
+		}
+		// a nested field is shown inside the value of its parent
+		if part == PartKey || part == PartValue && field != this.Field && !strings.HasPrefix(this.Field, field+".") {
+			return nil
+		}
+	}
+	return this.Re
+}
+
+// the value of a tag, or of a field nested in it with a dotted path like
+// user.id (or items.0.id in arrays)
+func (this Line) lookup(field string) (json.RawMessage, bool) {
+	if v, ok := this.Tags[field]; ok {
+		return v, true
+	}
+	return lookupPath(this.Tags, field)
+}
+
+func lookupPath(obj map[string]json.RawMessage, path string) (json.RawMessage, bool) {
+	for i := len(path) - 1; i > 0; i-- { // the longest key first, keys can have dots too
+		if path[i] != '.' {
+			continue
+		}
+		if v, ok := obj[path[:i]]; ok {
+			if out, ok := lookupIn(v, path[i+1:]); ok {
+				return out, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// the field at path, inside the object or array v
+func lookupIn(v json.RawMessage, path string) (json.RawMessage, bool) {
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(v, &obj) == nil {
+		if out, ok := obj[path]; ok {
+			return out, true
+		}
+		return lookupPath(obj, path)
+	}
+	var arr []json.RawMessage
+	if json.Unmarshal(v, &arr) != nil {
+		return nil, false
+	}
+	head, rest := path, ""
+	if i := strings.IndexByte(path, '.'); i >= 0 {
+		head, rest = path[:i], path[i+1:]
+	}
+	i, err := strconv.Atoi(head)
+	if err != nil || i < 0 || i >= len(arr) {
+		return nil, false
+	}
+	if rest == "" {
+		return arr[i], true
+	}
+	return lookupIn(arr[i], rest)
+}
+
+// Value returns a tag as a string, unquoted if it's a json string
+func (this Line) Value(tag string) string {
+	return unmarshalOrString(this.Tags[tag])
+}
+
+// the message shown for the line
+func (this Line) Message() string {
+	if this.Short == "" {
+		return this.Str
+	}
+	return this.Short
+}
@@ -0,0 +1,89 @@
+package tbuf
+
+import (
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	l := ParseLine(`{"message":"user Bob logged in","level":"info","id":"42","user":{"name":"bob"}}`, t.Log)
+	for _, c := range []struct {
+		q     string
+		mode  CaseMode
+		match bool
+	}{
+		{`id`, CaseSensitive, true},    // raw: the key
+		{`m:id`, CaseSensitive, false}, // not in the message
+		{`m:logged`, CaseSensitive, true},
+		{`v:42`, CaseSensitive, true},
+		{`v:^id$`, CaseSensitive, false},
+		{`k:^id$`, CaseSensitive, true},
+		{`k:42`, CaseSensitive, false},
+		{`id=^4`, CaseSensitive, true},
+		{`id=^2`, CaseSensitive, false},
+		{`user=bob`, CaseSensitive, true},
+		{`level=info`, CaseSensitive, true},
+		{`message=Bob`, CaseSensitive, true},
+		{`r:id=42`, CaseSensitive, false},
+		{`m:bob`, CaseSensitive, false},
+		{`m:bob`, CaseSmart, true},
+		{`m:BOB`, CaseSmart, false},
+		{`m:\Sob`, CaseSmart, true}, // escapes are not upper case
+		{`m:BOB`, CaseInsensitive, true},
+		{`m:(bob`, CaseInsensitive, false}, // literal
+	} {
+		q := ParseQuery(c.q, c.mode)
+		if got := q.Match(l); got != c.match {
+			t.Errorf("%q (%v): expected %v, got %v", c.q, c.mode, c.match, got)
+		}
+	}
+
+	// without the field, field=... is matched as typed
+	plain := ParseLine(`GET /api status=500 user=bob`, t.Log)
+	for q, match := range map[string]bool{`status=500`: true, `status=5\d\d`: true, `user=alice`: false} {
+		if got := ParseQuery(q, CaseSensitive).Match(plain); got != match {
+			t.Errorf("%q on a plain line: expected %v, got %v", q, match, got)
+		}
+	}
+
+	// dotted names are looked up in the nested objects and arrays
+	nested := ParseLine(`{"message":"x","user":{"id":42,"roles":["admin","dev"]},"a.b":{"c":"dots"}}`, t.Log)
+	for q, match := range map[string]bool{
+		`user.id=^42$`:       true,
+		`user.id=^4$`:        false,
+		`user.roles.1=^dev$`: true,
+		`user.roles.2=dev`:   false,
+		`a.b.c=dots`:         true,
+		`user.name=bob`:      false,
+		`user.id.x=42`:       false,
+	} {
+		if got := ParseQuery(q, CaseSensitive).Match(nested); got != match {
+			t.Errorf("%q on nested json: expected %v, got %v", q, match, got)
+		}
+	}
+}
+
+func TestQueryHighlight(t *testing.T) {
+	q := ParseQuery(`id=4`, CaseSensitive)
+	if q.Highlight(PartValue, "id") == nil || q.Highlight(PartValue, "user") != nil || q.Highlight(PartKey, "id") != nil {
+		t.Errorf("field scope should only highlight the field value")
+	}
+	if re := q.Highlight(PartMessage, ""); re == nil || !re.MatchString("id=4") || re.MatchString("4") {
+		t.Errorf("field scope should highlight the message as typed")
+	}
+	q = ParseQuery(`user.id=4`, CaseSensitive)
+	if q.Highlight(PartValue, "user") == nil || q.Highlight(PartValue, "use") != nil {
+		t.Errorf("a nested field should be highlighted in its parent")
+	}
+	q = ParseQuery(`k:id`, CaseSensitive)
+	if q.Highlight(PartKey, "id") == nil || q.Highlight(PartValue, "id") != nil {
+		t.Errorf("keys scope should only highlight the keys")
+	}
+	q = ParseQuery(`id`, CaseSensitive)
+	if q.Highlight(PartKey, "id") == nil || q.Highlight(PartMessage, "") == nil {
+		t.Errorf("raw scope should highlight everything")
+	}
+	q = nil
+	if q.Highlight(PartMessage, "") != nil {
+		t.Errorf("no query, no highlight")
+	}
+}
@@ -0,0 +1,251 @@
+package tbuf
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// lines starting with something that looks like a timestamp
+var ReStartsWithTime = regexp.MustCompile(`^\[?(\d{4}[-/]\d\d[-/]\d\d[T ]\d\d:\d\d|\d\d:\d\d:\d\d|[A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d)`)
+
+// continuation of java and python stack traces
+var ReStackTrace = regexp.MustCompile(`^(\s|at |Caused by:|Traceback |\.\.\. \d+ more|[\w.]+(Error|Exception)(:|$))`)
+
+// max number of lines of a json document before giving up
+const maxJSONLines = 10000
+
+// Joiner assembles physical lines into records, so that stack traces and
+// pretty printed json end up in a single Line
+type Joiner struct {
+	Continue *regexp.Regexp // lines matching are appended to the previous record
+	Start    *regexp.Regexp // if set, lines not matching are appended to the previous record
+	JSON     bool           // join lines until a json document is complete
+
+	rec []string
+	doc *jsonPrefix // while inside a json document
+}
+
+// Push adds a line, and emits any record that is complete
+func (this *Joiner) Push(l string, emit func(string)) {
+	if this.doc != nil { // inside a json document
+		if !this.doc.feed(l) { // not json after all, the line starts something else
+			this.Flush(emit)
+			this.Push(l, emit)
+			return
+		}
+		this.rec = append(this.rec, l)
+		if this.doc.complete() || len(this.rec) >= maxJSONLines {
+			this.Flush(emit)
+		}
+		return
+	}
+	if this.JSON {
+		t := strings.TrimLeft(l, " \t")
+		if len(t) > 0 && (t[0] == '{' || t[0] == '[') {
+			if doc := (&jsonPrefix{}); doc.feed(l) {
+				this.Flush(emit)
+				this.rec = []string{l}
+				this.doc = doc
+				if doc.complete() {
+					this.Flush(emit)
+				}
+				return
+			}
+		}
+	}
+	if len(this.rec) > 0 && this.continues(l) {
+		this.rec = append(this.rec, l)
+		return
+	}
+	this.Flush(emit)
+	this.rec = []string{l}
+	if this.Continue == nil && this.Start == nil {
+		this.Flush(emit) // nothing can be joined, no need to wait
+	}
+}
+
+// Idle emits the pending record, nothing else is coming soon
+func (this *Joiner) Idle(emit func(string)) {
+	this.Flush(emit)
+}
+
+// Flush emits the pending record, if any. The lines of an incomplete json
+// document are emitted one by one
+func (this *Joiner) Flush(emit func(string)) {
+	if len(this.rec) == 0 {
+		return
+	}
+	rec, doc := this.rec, this.doc
+	this.rec = nil
+	this.doc = nil
+	if doc != nil && !doc.complete() {
+		for _, l := range rec {
+			emit(l)
+		}
+		return
+	}
+	s := strings.Join(rec, "\n")
+	if doc != nil && len(rec) > 1 {
+		b := &bytes.Buffer{}
+		if json.Compact(b, []byte(s)) == nil {
+			s = b.String()
+		}
+	}
+	emit(s)
+}
+
+func (this *Joiner) continues(l string) bool {
+	if this.Start != nil && !this.Start.MatchString(l) {
+		return true
+	}
+	if this.Continue != nil && this.Continue.MatchString(l) {
+		return true
+	}
+	return false
+}
+
+// what is expected next in a json document
+const (
+	jsValue      = iota
+	jsValueOrEnd // after [
+	jsKeyOrEnd   // after {
+	jsKey        // after , in an object
+	jsColon
+	jsNext // , or the end of the array or object
+	jsDone // the document is complete, nothing else can follow
+)
+
+var reJSONNumber = regexp.MustCompile(`^-?(0|[1-9]\d*)(\.\d+)?([eE][+-]?\d+)?$`)
+
+// checks, one line at a time, that a json document can still be valid
+type jsonPrefix struct {
+	stack  []byte // the open { and [
+	state  int
+	lit    []byte // a number, true, false or null
+	inStr  bool
+	esc    bool
+	strKey bool
+	bad    bool
+}
+
+// feed a line, false if it can't be json
+func (this *jsonPrefix) feed(l string) bool {
+	for i := 0; i < len(l) && !this.bad; i++ {
+		this.next(l[i])
+	}
+	this.endLiteral()
+	if this.inStr { // a string can't span lines
+		this.bad = true
+	}
+	return !this.bad
+}
+
+func (this *jsonPrefix) complete() bool {
+	return !this.bad && this.state == jsDone
+}
+
+func (this *jsonPrefix) next(c byte) {
+	if this.inStr {
+		switch {
+		case this.esc:
+			this.esc = false
+		case c == '\\':
+			this.esc = true
+		case c == '"':
+			this.inStr = false
+			if this.strKey {
+				this.state = jsColon
+			} else {
+				this.valueDone()
+			}
+		}
+		return
+	}
+	if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '+' || c == '-' {
+		if len(this.lit) == 0 && this.state != jsValue && this.state != jsValueOrEnd {
+			this.bad = true
+		}
+		this.lit = append(this.lit, c)
+		return
+	}
+	this.endLiteral()
+	if this.bad {
+		return
+	}
+	switch c {
+	case ' ', '\t', '\r':
+	case '"':
+		switch this.state {
+		case jsValue, jsValueOrEnd:
+			this.inStr, this.strKey = true, false
+		case jsKeyOrEnd, jsKey:
+			this.inStr, this.strKey = true, true
+		default:
+			this.bad = true
+		}
+	case '{', '[':
+		if this.state != jsValue && this.state != jsValueOrEnd {
+			this.bad = true
+			return
+		}
+		this.stack = append(this.stack, c)
+		this.state = jsKeyOrEnd
+		if c == '[' {
+			this.state = jsValueOrEnd
+		}
+	case '}', ']':
+		open := byte('{')
+		if c == ']' {
+			open = '['
+		}
+		n := len(this.stack)
+		ok := this.state == jsNext || c == '}' && this.state == jsKeyOrEnd || c == ']' && this.state == jsValueOrEnd
+		if !ok || n == 0 || this.stack[n-1] != open {
+			this.bad = true
+			return
+		}
+		this.stack = this.stack[:n-1]
+		this.valueDone()
+	case ':':
+		if this.state != jsColon {
+			this.bad = true
+			return
+		}
+		this.state = jsValue
+	case ',':
+		if this.state != jsNext {
+			this.bad = true
+			return
+		}
+		this.state = jsValue
+		if this.stack[len(this.stack)-1] == '{' {
+			this.state = jsKey
+		}
+	default:
+		this.bad = true
+	}
+}
+
+// the number or literal being read is complete
+func (this *jsonPrefix) endLiteral() {
+	if len(this.lit) == 0 {
+		return
+	}
+	switch l := string(this.lit); {
+	case l == "true", l == "false", l == "null", reJSONNumber.MatchString(l):
+		this.valueDone()
+	default:
+		this.bad = true
+	}
+	this.lit = nil
+}
+
+func (this *jsonPrefix) valueDone() {
+	if len(this.stack) == 0 {
+		this.state = jsDone
+	} else {
+		this.state = jsNext
+	}
+}
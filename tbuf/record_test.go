@@ -0,0 +1,120 @@
+package tbuf
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func joinAll(j *Joiner, lines ...string) (out []string) {
+	emit := func(s string) {
+		out = append(out, s)
+	}
+	for _, l := range lines {
+		j.Push(l, emit)
+	}
+	j.Flush(emit)
+	return
+}
+
+func TestJoinerStack(t *testing.T) {
+	out := joinAll(&Joiner{Continue: ReStackTrace},
+		"2024-01-01 ERROR boom",
+		"java.lang.IllegalStateException: boom",
+		"\tat foo.Bar(Bar.java:12)",
+		"\t... 3 more",
+		"2024-01-01 INFO next",
+	)
+	if len(out) != 2 {
+		t.Fatalf("expected 2 records, got %q", out)
+	}
+	if out[0] != "2024-01-01 ERROR boom\njava.lang.IllegalStateException: boom\n\tat foo.Bar(Bar.java:12)\n\t... 3 more" {
+		t.Fatalf("bad record: %q", out[0])
+	}
+}
+
+func TestJoinerStart(t *testing.T) {
+	out := joinAll(&Joiner{Start: ReStartsWithTime},
+		"banner",
+		"2024-01-01 10:00:00 ERROR boom",
+		"Traceback (most recent call last):",
+		"ValueError: x",
+		"2024-01-01 10:00:01 INFO ok",
+	)
+	if len(out) != 3 {
+		t.Fatalf("expected 3 records, got %q", out)
+	}
+	if out[1] != "2024-01-01 10:00:00 ERROR boom\nTraceback (most recent call last):\nValueError: x" {
+		t.Fatalf("bad record: %q", out[1])
+	}
+}
+
+func TestJoinerJSON(t *testing.T) {
+	out := joinAll(&Joiner{JSON: true, Continue: regexp.MustCompile(`^\s`)},
+		`{"message": "one"}`,
+		`{`,
+		`  "message": "two }",`,
+		`  "tags": [1, 2]`,
+		`}`,
+		`plain`,
+		`  indented`,
+	)
+	if len(out) != 3 {
+		t.Fatalf("expected 3 records, got %q", out)
+	}
+	if out[1] != `{"message":"two }","tags":[1,2]}` {
+		t.Fatalf("bad json record: %q", out[1])
+	}
+	if out[2] != "plain\n  indented" {
+		t.Fatalf("bad record: %q", out[2])
+	}
+}
+
+func TestJoinerNotJSON(t *testing.T) {
+	out := joinAll(&Joiner{JSON: true},
+		`[INFO] starting {`,
+		`{ not json`,
+		`[`,
+		`2024-01-02 10:00:00 next`,
+		`{"a": "odd quote}`,
+		`plain`,
+		`{"a": [1,`,
+		`  2], "b": tru`,
+		`e}`,
+		`{"a": 1} trailing`,
+	)
+	exp := []string{
+		`[INFO] starting {`,
+		`{ not json`,
+		`[`,
+		`2024-01-02 10:00:00 next`,
+		`{"a": "odd quote}`,
+		`plain`,
+		`{"a": [1,`,
+		`  2], "b": tru`,
+		`e}`,
+		`{"a": 1} trailing`,
+	}
+	if !reflect.DeepEqual(out, exp) {
+		t.Fatalf("expected the lines as they are, got %q", out)
+	}
+}
+
+func TestJoinerJSONIdle(t *testing.T) {
+	var out []string
+	emit := func(s string) {
+		out = append(out, s)
+	}
+	j := &Joiner{JSON: true}
+	j.Push(`{`, emit)
+	j.Push(`  "a": [1, 2.5e3, null, {}],`, emit)
+	j.Idle(emit)
+	if !reflect.DeepEqual(out, []string{`{`, `  "a": [1, 2.5e3, null, {}],`}) {
+		t.Fatalf("expected the incomplete document flushed, got %q", out)
+	}
+	j.Push(`{"b": {"c": "}"},`, emit)
+	j.Push(` "d": false}`, emit)
+	if len(out) != 3 || out[2] != `{"b":{"c":"}"},"d":false}` {
+		t.Fatalf("bad json record: %q", out)
+	}
+}
@@ -0,0 +1,73 @@
+package tbuf
+
+import (
+	"sort"
+	"time"
+)
+
+// Sorted tells if the lines are in chronological order
+func (this *Buffer) Sorted() bool {
+	this.m.Lock()
+	defer this.m.Unlock()
+	return !this.unsorted
+}
+
+// Latest returns the most recent time of the lines
+func (this *Buffer) Latest() time.Time {
+	this.m.Lock()
+	defer this.m.Unlock()
+	return this.latest
+}
+
+// TimeAt returns the time of the line, or of the closest line before it with a time
+func (this *Buffer) TimeAt(pos int) time.Time {
+	this.m.Lock()
+	defer this.m.Unlock()
+	return this.timeAt(pos)
+}
+
+func (this *Buffer) timeAt(pos int) time.Time {
+	if pos >= len(this.Lines) {
+		pos = len(this.Lines) - 1
+	}
+	for ; pos >= 0; pos-- {
+		if t := this.Lines[pos].Time; !t.IsZero() {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Seek returns the position of the first line at or after t, binary
+// searching if the lines are sorted
+func (this *Buffer) Seek(t time.Time) int {
+	this.m.Lock()
+	defer this.m.Unlock()
+	if !this.unsorted {
+		return sort.Search(len(this.Lines), func(i int) bool {
+			lt := this.timeAt(i)
+			return !lt.IsZero() && !lt.Before(t)
+		})
+	}
+	for i, l := range this.Lines {
+		if !l.Time.IsZero() && !l.Time.Before(t) {
+			return i
+		}
+	}
+	return len(this.Lines)
+}
+
+// Window returns a new buffer with the lines between from and to (excluded).
+// Lines without a time are considered at the time of the previous one
+func (this *Buffer) Window(from, to time.Time) *Buffer {
+	var cur time.Time
+	return this.Filter(func(l Line) bool {
+		if !l.Time.IsZero() {
+			cur = l.Time
+		}
+		if cur.IsZero() {
+			return false
+		}
+		return (from.IsZero() || !cur.Before(from)) && (to.IsZero() || cur.Before(to))
+	})
+}
@@ -0,0 +1,44 @@
+package tbuf
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSeek(t *testing.T) {
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	b := &Buffer{}
+	for i := 0; i < 10; i++ {
+		b.Append(fmt.Sprintf(`{"time":"%s","msg":"%d"}`, base.Add(time.Duration(i)*time.Minute).Format(time.RFC3339), i), t.Log)
+		b.Append("\tcontinuation", t.Log)
+	}
+	if !b.Sorted() {
+		t.Fatalf("expected sorted")
+	}
+	if p := b.Seek(base.Add(3 * time.Minute)); p != 6 {
+		t.Errorf("expected 6, got %d", p)
+	}
+	if p := b.Seek(base.Add(150 * time.Second)); p != 6 {
+		t.Errorf("expected 6, got %d", p)
+	}
+	if p := b.Seek(base.Add(time.Hour)); p != 20 {
+		t.Errorf("expected the end, got %d", p)
+	}
+	if p := b.Seek(time.Time{}); p != 0 {
+		t.Errorf("expected the start, got %d", p)
+	}
+
+	w := b.Window(base.Add(2*time.Minute), base.Add(4*time.Minute))
+	if len(w.Lines) != 4 || w.Lines[0].Short != "2" || w.Lines[3].Str != "\tcontinuation" {
+		t.Errorf("bad window: %+v", w.Lines)
+	}
+
+	b.Append(fmt.Sprintf(`{"time":"%s","msg":"old"}`, base.Format(time.RFC3339)), t.Log)
+	if b.Sorted() {
+		t.Fatalf("expected unsorted")
+	}
+	if p := b.Seek(base.Add(3 * time.Minute)); p != 6 {
+		t.Errorf("expected 6, got %d", p)
+	}
+}
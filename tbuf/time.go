@@ -0,0 +1,129 @@
+package tbuf
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// zone used for timestamps which don't have one
+var Location = time.Local
+
+// user supplied layouts, tried before the common ones
+var TimeLayouts []string
+
+var commonLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 Z07:00",
+	"2006-01-02 15:04:05 -0700 MST", // go time.String()
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"02/Jan/2006:15:04:05 -0700", // apache
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.RubyDate,
+	time.UnixDate,
+	time.ANSIC,
+	time.Stamp, // syslog, no year
+	"2006-01-02",
+}
+
+// ParseTime parses a json value as a timestamp, either as a string in one
+// of the known layouts, or as an epoch in seconds, millis, micros or nanos
+func ParseTime(j json.RawMessage) (time.Time, bool) {
+	if len(j) == 0 {
+		return time.Time{}, false
+	}
+	if j[0] == '"' {
+		var s string
+		if json.Unmarshal(j, &s) != nil {
+			return time.Time{}, false
+		}
+		return ParseTimeString(s)
+	}
+	return parseEpoch(string(j))
+}
+
+// ParseTimeString parses a timestamp in one of the known layouts, or as epoch
+func ParseTimeString(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range TimeLayouts {
+		if t, ok := parseLayout(layout, s); ok {
+			return t, true
+		}
+	}
+	if t, ok := parseEpoch(s); ok {
+		return t, true
+	}
+	for _, layout := range commonLayouts {
+		if t, ok := parseLayout(layout, s); ok {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func parseLayout(layout, s string) (time.Time, bool) {
+	t, err := time.ParseInLocation(layout, s, Location)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if t.Year() == 0 { // no year in the layout, guess the most recent
+		now := time.Now()
+		t = t.AddDate(now.Year(), 0, 0)
+		if t.After(now.Add(24 * time.Hour)) {
+			t = t.AddDate(-1, 0, 0)
+		}
+	}
+	return t, true
+}
+
+// guess the unit from the magnitude: seconds up to year 5000, then millis, micros and nanos
+func parseEpoch(s string) (time.Time, bool) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		abs := i
+		if abs < 0 {
+			abs = -abs
+		}
+		switch {
+		case abs < 1e11:
+			return time.Unix(i, 0), true
+		case abs < 1e14: // split, as nanos they would overflow after 2262
+			return time.Unix(i/1e3, i%1e3*1e6), true
+		case abs < 1e17:
+			return time.Unix(i/1e6, i%1e6*1e3), true
+		default:
+			return time.Unix(0, i), true
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return time.Time{}, false
+	}
+	abs := math.Abs(f)
+	switch {
+	case abs < 1e11:
+	case abs < 1e14:
+		f /= 1e3
+	case abs < 1e17:
+		f /= 1e6
+	default:
+		f /= 1e9
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e6))*1e3), true
+}
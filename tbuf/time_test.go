@@ -0,0 +1,73 @@
+package tbuf
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	Location = time.UTC
+	defer func() { Location = time.Local }()
+	exp := time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)
+	for _, j := range []string{
+		`1700000000`,
+		`1700000000000`,
+		`1700000000000000`,
+		`1700000000000000000`,
+		`"1700000000000"`,
+		`1700000000.0`,
+		`"2023-11-14T22:13:20Z"`,
+		`"2023-11-14T23:13:20+01:00"`,
+		`"2023-11-14T22:13:20"`,
+		`"2023-11-14 22:13:20"`,
+		`"2023/11/14 22:13:20"`,
+		`"14/Nov/2023:22:13:20 +0000"`,
+	} {
+		got, ok := ParseTime(json.RawMessage(j))
+		if !ok {
+			t.Errorf("can't parse %s", j)
+			continue
+		}
+		if !got.Equal(exp) {
+			t.Errorf("%s: expected %v, got %v", j, exp, got.UTC())
+		}
+	}
+
+	got, ok := ParseTime(json.RawMessage(`1700000000.123`))
+	if !ok || got.Sub(exp) != 123*time.Millisecond {
+		t.Errorf("float epoch: got %v", got.UTC())
+	}
+	// as nanos, millis and micros after 2262 would overflow
+	for j, exp := range map[string]time.Time{
+		`99999999999999`:    time.Unix(99999999999, 999e6),
+		`99999999999999999`: time.Unix(99999999999, 999999e3),
+		`-99999999999999`:   time.Unix(-99999999999, -999e6),
+	} {
+		got, ok := ParseTime(json.RawMessage(j))
+		if !ok || !got.Equal(exp) || got.Year() < 0 != (j[0] == '-') {
+			t.Errorf("%s: expected %v, got %v", j, exp.UTC(), got.UTC())
+		}
+	}
+	got, ok = ParseTime(json.RawMessage(`"2023-11-14 22:13:20,123"`))
+	if !ok || got.Sub(exp) != 123*time.Millisecond {
+		t.Errorf("comma millis: got %v", got.UTC())
+	}
+
+	Location = time.FixedZone("X", 3600)
+	got, ok = ParseTime(json.RawMessage(`"2023-11-14 23:13:20"`))
+	if !ok || !got.Equal(exp) {
+		t.Errorf("default zone: got %v", got.UTC())
+	}
+
+	TimeLayouts = []string{"20060102.150405"}
+	defer func() { TimeLayouts = nil }()
+	got, ok = ParseTime(json.RawMessage(`"20231114.231320"`))
+	if !ok || !got.Equal(exp) {
+		t.Errorf("custom layout: got %v", got.UTC())
+	}
+
+	if _, ok := ParseTime(json.RawMessage(`"not a time"`)); ok {
+		t.Errorf("parsed garbage")
+	}
+}
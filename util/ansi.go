@@ -0,0 +1,59 @@
+package util
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CSI, OSC and two bytes escape sequences
+var reANSI = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)?|\x1b[@-Z\\-_]`)
+
+func HasANSI(s string) bool {
+	return strings.IndexByte(s, 0x1b) >= 0
+}
+
+// StripANSI removes all the escape sequences
+func StripANSI(s string) string {
+	if !HasANSI(s) {
+		return s
+	}
+	return reANSI.ReplaceAllString(s, "")
+}
+
+// SplitANSI calls f for each piece of text, and for each SGR (colors and
+// attributes) sequence with its parameters. Any other sequence is dropped
+func SplitANSI(s string, f func(text string, sgr []int)) {
+	for {
+		m := reANSI.FindStringIndex(s)
+		if m == nil {
+			break
+		}
+		if m[0] > 0 {
+			f(s[:m[0]], nil)
+		}
+		seq := s[m[0]:m[1]]
+		if strings.HasPrefix(seq, "\x1b[") && strings.HasSuffix(seq, "m") {
+			f("", parseSGR(seq[2:len(seq)-1]))
+		}
+		s = s[m[1]:]
+	}
+	if s != "" {
+		f(s, nil)
+	}
+}
+
+func parseSGR(s string) []int {
+	if s == "" {
+		return []int{0}
+	}
+	out := []int{}
+	for _, p := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == ':' }) {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			n = 0
+		}
+		out = append(out, n)
+	}
+	return out
+}
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestANSI(t *testing.T) {
+	s := "\x1b[1;31mERROR\x1b[0m \x1b]0;title\x07msg \x1b[38;5;226mx\x1b[m\x1b[K"
+	if StripANSI(s) != "ERROR msg x" {
+		t.Fatalf("bad strip: %q", StripANSI(s))
+	}
+	type part struct {
+		text string
+		sgr  []int
+	}
+	var parts []part
+	SplitANSI(s, func(text string, sgr []int) {
+		parts = append(parts, part{text, sgr})
+	})
+	exp := []part{
+		{"", []int{1, 31}},
+		{"ERROR", nil},
+		{"", []int{0}},
+		{" ", nil},
+		{"msg ", nil},
+		{"", []int{38, 5, 226}},
+		{"x", nil},
+		{"", []int{0}},
+	}
+	if !reflect.DeepEqual(parts, exp) {
+		t.Fatalf("expected %v, got %v", exp, parts)
+	}
+}
@@ -0,0 +1,117 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	fuzzyMatch       = 16
+	fuzzyConsecutive = 8
+	fuzzyBoundary    = 8
+	fuzzyGapStart    = -3
+	fuzzyGapExtend   = -1
+)
+
+// FuzzyScore matches each word of the pattern as a subsequence of s, ignoring
+// case, like fzf does. Higher is better, consecutive chars and chars at the
+// start of words count more
+func FuzzyScore(pattern, s string) (int, bool) {
+	words := strings.Fields(pattern)
+	if len(words) == 0 {
+		return 0, false
+	}
+	text := []rune(strings.ToLower(s))
+	orig := []rune(s)
+	if len(orig) != len(text) { // lowering changed the length, compare as is
+		text = orig
+	}
+	score := 0
+	for _, w := range words {
+		sc, ok := fuzzyWord([]rune(strings.ToLower(w)), text, orig)
+		if !ok {
+			return 0, false
+		}
+		score += sc
+	}
+	return score, true
+}
+
+// find the shortest window matching p, then score it
+func fuzzyWord(p, text, orig []rune) (int, bool) {
+	best, found := 0, false
+	for start := 0; start < len(text); start++ {
+		if text[start] != p[0] {
+			continue
+		}
+		// forward, to the end of the first match
+		pi, end := 0, -1
+		for i := start; i < len(text); i++ {
+			if text[i] == p[pi] {
+				pi++
+				if pi == len(p) {
+					end = i
+					break
+				}
+			}
+		}
+		if end < 0 {
+			break // no more matches after this
+		}
+		// backward, to the shortest window
+		pi = len(p) - 1
+		from := end
+		for i := end; i >= start; i-- {
+			if text[i] == p[pi] {
+				pi--
+				if pi < 0 {
+					from = i
+					break
+				}
+			}
+		}
+		sc := fuzzyWindow(p, text, orig, from, end)
+		if !found || sc > best {
+			best, found = sc, true
+		}
+		start = from // the next windows start after this one
+	}
+	return best, found
+}
+
+func fuzzyWindow(p, text, orig []rune, from, to int) int {
+	score, pi, gap, prev := 0, 0, false, -2
+	for i := from; i <= to && pi < len(p); i++ {
+		if text[i] != p[pi] {
+			if gap {
+				score += fuzzyGapExtend
+			} else {
+				score += fuzzyGapStart
+				gap = true
+			}
+			continue
+		}
+		score += fuzzyMatch
+		if prev == i-1 {
+			score += fuzzyConsecutive
+		}
+		if i == 0 || isBoundary(orig[i-1], orig[i]) {
+			score += fuzzyBoundary
+		}
+		gap = false
+		prev = i
+		pi++
+	}
+	return score
+}
+
+// the start of a word, or of a camelCase hump
+func isBoundary(prev, cur rune) bool {
+	switch {
+	case !unicode.IsLetter(prev) && !unicode.IsDigit(prev):
+		return unicode.IsLetter(cur) || unicode.IsDigit(cur)
+	case unicode.IsLower(prev) && unicode.IsUpper(cur):
+		return true
+	}
+	return false
+}
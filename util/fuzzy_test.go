@@ -0,0 +1,46 @@
+package util
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	for _, s := range []string{
+		"connection refused by upstream",
+		"Conn refused, upstream=api",
+		"ConnectionRefused upstream",
+	} {
+		if _, ok := FuzzyScore("conn refsd upstrm", s); !ok {
+			t.Errorf("expected %q to match", s)
+		}
+	}
+	if _, ok := FuzzyScore("conn refsd upstrm", "connection reset by upstream"); ok {
+		t.Errorf("expected no match without refsd")
+	}
+	if _, ok := FuzzyScore("conn refsd upstrm", "upstream refused conn"); !ok {
+		t.Errorf("expected the words to match in any order")
+	}
+	if _, ok := FuzzyScore("", "anything"); ok {
+		t.Errorf("empty pattern should not match")
+	}
+
+	// better matches first
+	list := []string{
+		"t-i-m-e-o-u-t",
+		"request timed out",
+		"timeout",
+		"the timeout was hit",
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		a, _ := FuzzyScore("timeout", list[i])
+		b, _ := FuzzyScore("timeout", list[j])
+		return a > b
+	})
+	if list[0] != "timeout" && list[0] != "the timeout was hit" || list[3] != "t-i-m-e-o-u-t" {
+		t.Errorf("bad order: %q", list)
+	}
+	if _, ok := FuzzyScore("timeout", "request timed out"); !ok {
+		t.Errorf("expected a subsequence match")
+	}
+}
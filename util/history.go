@@ -0,0 +1,135 @@
+package util
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// HistoryFile keeps the queries across sessions, safe for more instances
+// writing at the same time
+type HistoryFile struct {
+	Path string
+	Max  int // how many entries to keep
+}
+
+// StatePath returns the path of a file in the XDG state dir (~/.local/state/jl/...)
+func StatePath(name string) string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "jl", name)
+}
+
+// Load returns the entries, from the oldest
+func (this *HistoryFile) Load() ([]string, error) {
+	if this == nil || this.Path == "" {
+		return nil, nil
+	}
+	unlock, err := this.lock(unix.LOCK_SH)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return this.read()
+}
+
+// Add appends an entry, removing any older duplicate
+func (this *HistoryFile) Add(s string) error {
+	if this == nil || this.Path == "" || s == "" || strings.ContainsAny(s, "\r\n") {
+		return nil
+	}
+	unlock, err := this.lock(unix.LOCK_EX)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// others may have written in the meantime, merge with what is there
+	list, err := this.read()
+	if err != nil {
+		return err
+	}
+	list = append(dedup(list, s), s)
+	if this.Max > 0 && len(list) > this.Max {
+		list = list[len(list)-this.Max:]
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(this.Path), ".history")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	for _, l := range list {
+		w.WriteString(l + "\n")
+	}
+	err = w.Flush()
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), this.Path)
+}
+
+func (this *HistoryFile) read() ([]string, error) {
+	b, err := ioutil.ReadFile(this.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, l := range strings.Split(string(b), "\n") {
+		if l != "" {
+			out = append(dedup(out, l), l)
+		}
+	}
+	return out, nil
+}
+
+// a separate lock file, since the history file is replaced on write
+func (this *HistoryFile) lock(how int) (func(), error) {
+	err := os.MkdirAll(filepath.Dir(this.Path), 0700)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(this.Path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	err = unix.Flock(int(f.Fd()), how)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		unix.Flock(int(f.Fd()), unix.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// remove s from the list
+func dedup(list []string, s string) []string {
+	out := list[:0]
+	for _, l := range list {
+		if l != s {
+			out = append(out, l)
+		}
+	}
+	return out
+}
@@ -0,0 +1,50 @@
+package util
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHistoryFile(t *testing.T) {
+	f := &HistoryFile{Path: filepath.Join(t.TempDir(), "sub", "history"), Max: 3}
+	other := &HistoryFile{Path: f.Path, Max: 3} // another instance
+	for _, s := range []string{"a", "b", "a", "c"} {
+		if err := f.Add(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	other.Add("d")
+	list, err := f.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"a", "c", "d"}; !reflect.DeepEqual(list, exp) {
+		t.Fatalf("expected %v, got %v", exp, list)
+	}
+
+	h, err := NewHistoryInput(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Get().String() != "d" {
+		t.Fatalf("expected the last entry, got %q", h.Get())
+	}
+	h.NewUnlessEmpty().Append("a")
+	if err := h.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.History) != 3 || h.History[2].String() != "a" || h.Pos != 2 {
+		t.Fatalf("bad commit: %v", h.History)
+	}
+	if i := h.Search("c", len(h.History)); i != 0 {
+		t.Fatalf("expected to find c at 0, got %d", i)
+	}
+	if i := h.Search("c", 0); i != -1 {
+		t.Fatalf("expected nothing, got %d", i)
+	}
+	list, _ = other.Load()
+	if exp := []string{"c", "d", "a"}; !reflect.DeepEqual(list, exp) {
+		t.Fatalf("expected %v, got %v", exp, list)
+	}
+}
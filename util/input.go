@@ -1,5 +1,10 @@
 package util
 
+import (
+	"strings"
+	"unicode"
+)
+
 type Input struct {
 	left  string
 	right string
@@ -13,27 +18,137 @@ func (this *Input) Details() (string, string) {
 	return this.left, this.right
 }
 
+func (this *Input) Set(s string) {
+	this.left, this.right = s, ""
+}
+
+// ReplaceBefore replaces old, right before the cursor, with s
+func (this *Input) ReplaceBefore(old, s string) {
+	if strings.HasSuffix(this.left, old) {
+		this.left = this.left[:len(this.left)-len(old)] + s
+	}
+}
+
 func (this *Input) Append(s string) {
 	this.left = this.left + s
 }
 
 func (this *Input) Backspace() {
-	Chop(&this.left)
+	this.left = this.left[:len(this.left)-len(lastGrapheme(this.left))]
+}
+
+func (this *Input) Delete() {
+	this.right = this.right[len(firstGrapheme(this.right)):]
 }
 
 func (this *Input) Left() {
-	ch := Chop(&this.left)
+	ch := lastGrapheme(this.left)
+	this.left = this.left[:len(this.left)-len(ch)]
 	this.right = ch + this.right
 }
 
 func (this *Input) Right() {
-	ch := Shift(&this.right)
+	ch := firstGrapheme(this.right)
+	this.right = this.right[len(ch):]
 	this.left += ch
 }
 
+func (this *Input) Home() {
+	this.left, this.right = "", this.left+this.right
+}
+
+func (this *Input) End() {
+	this.left, this.right = this.left+this.right, ""
+}
+
+// delete everything before the cursor
+func (this *Input) KillLeft() {
+	this.left = ""
+}
+
+// delete everything after the cursor
+func (this *Input) KillRight() {
+	this.right = ""
+}
+
+// delete the word before the cursor, up to a space
+func (this *Input) DeleteWord() {
+	s := strings.TrimRightFunc(this.left, unicode.IsSpace)
+	i := strings.LastIndexFunc(s, unicode.IsSpace)
+	this.left = s[:i+1]
+}
+
+// move to the start of the word before the cursor
+func (this *Input) WordLeft() {
+	s := strings.TrimRightFunc(this.left, notWord)
+	i := strings.LastIndexFunc(s, notWord)
+	this.left, this.right = s[:i+1], this.left[i+1:]+this.right
+}
+
+// move to the end of the word after the cursor
+func (this *Input) WordRight() {
+	s := strings.TrimLeftFunc(this.right, notWord)
+	i := strings.IndexFunc(s, notWord)
+	if i < 0 {
+		i = len(s)
+	}
+	i += len(this.right) - len(s)
+	this.left, this.right = this.left+this.right[:i], this.right[i:]
+}
+
+func notWord(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+}
+
 type HistoryInput struct {
 	Pos     int
 	History []*Input
+	File    *HistoryFile
+}
+
+// NewHistoryInput loads the previous entries from the file
+func NewHistoryInput(file *HistoryFile) (*HistoryInput, error) {
+	this := &HistoryInput{File: file}
+	list, err := file.Load()
+	for _, s := range list {
+		this.History = append(this.History, &Input{left: s})
+	}
+	this.Pos = len(this.History) - 1
+	return this, err
+}
+
+// Commit moves the current entry to the end, removing duplicates, and saves it to the file
+func (this *HistoryInput) Commit() error {
+	if this.Pos < 0 || this.Pos >= len(this.History) {
+		return nil
+	}
+	cur := this.History[this.Pos]
+	s := cur.String()
+	if s == "" {
+		return nil
+	}
+	list := this.History[:0]
+	for _, i := range this.History {
+		if i != cur && i.String() != s {
+			list = append(list, i)
+		}
+	}
+	this.History = append(list, cur)
+	this.Pos = len(this.History) - 1
+	return this.File.Add(s)
+}
+
+// Search returns the most recent entry before the given position containing s, or -1
+func (this *HistoryInput) Search(s string, before int) int {
+	if before > len(this.History) {
+		before = len(this.History)
+	}
+	for i := before - 1; i >= 0; i-- {
+		if strings.Contains(this.History[i].String(), s) {
+			return i
+		}
+	}
+	return -1
 }
 
 func (this *HistoryInput) Get() *Input {
@@ -28,3 +28,51 @@ func TestInput(t *testing.T) {
 	}
 
 }
+
+func TestInputRunes(t *testing.T) {
+	i := &Input{}
+	i.Append("aé日é")
+	i.Backspace() // e + combining acute
+	i.Left()
+	i.Left()
+	i.Append("x")
+	if i.String() != "axé日" {
+		t.Fatalf("expected axé日, got %q", i)
+	}
+	i.Right()
+	i.Delete()
+	if l, r := i.Details(); l != "axé" || r != "" {
+		t.Fatalf("expected axé|, got %q|%q", l, r)
+	}
+}
+
+func TestInputReadline(t *testing.T) {
+	i := &Input{}
+	i.Append("foo bar.baz  qux")
+	i.DeleteWord()
+	if i.String() != "foo bar.baz  " {
+		t.Fatalf("ctrl-w: got %q", i)
+	}
+	i.WordLeft()
+	if l, _ := i.Details(); l != "foo bar." {
+		t.Fatalf("alt-b: got %q", l)
+	}
+	i.WordLeft()
+	i.WordRight()
+	if l, _ := i.Details(); l != "foo bar" {
+		t.Fatalf("alt-f: got %q", l)
+	}
+	i.KillRight()
+	i.Home()
+	i.Delete()
+	i.End()
+	i.Append("!")
+	if i.String() != "oo bar!" {
+		t.Fatalf("got %q", i)
+	}
+	i.Left()
+	i.KillLeft()
+	if i.String() != "!" {
+		t.Fatalf("ctrl-u: got %q", i)
+	}
+}
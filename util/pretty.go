@@ -1,6 +1,7 @@
 package util
 
 import (
+	"bytes"
 	"encoding/json"
 	"regexp"
 	"strings"
@@ -13,15 +14,14 @@ var reLong = regexp.MustCompile(`[^\n]{80}\S*? +`) // a long line, with no /n
 func Prettify(s string) []string {
 	s = strings.ReplaceAll(s, "\n", "␍\n")
 	s = reJson.ReplaceAllStringFunc(s, func(in string) string {
-		var v interface{}
-		err := json.Unmarshal([]byte(in), &v)
+		b := &bytes.Buffer{}
+		err := json.Indent(b, []byte(in), "", "  ") // keeps the order of the keys
 		if err != nil {
 			// multiple json?
 			//log.Printf("multiple json? %s", s)
 			return in
 		}
-		j, _ := json.MarshalIndent(v, "", "  ")
-		return string(j)
+		return b.String()
 	})
 	s = reLong.ReplaceAllStringFunc(s, func(in string) string {
 		return strings.TrimRight(in, " ") + "\n"
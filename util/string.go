@@ -1,26 +1,37 @@
 package util
 
+import (
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
 // very perlish, i know
 func Chop(s *string) string {
 	in := *s
-	l := len(in)
-	switch l {
-	case 0:
-		return ""
-	default:
-		*s = in[0 : l-1]
-		return in[l-1:]
-	}
+	_, l := utf8.DecodeLastRuneInString(in)
+	*s = in[0 : len(in)-l]
+	return in[len(in)-l:]
 }
 
 func Shift(s *string) string {
 	in := *s
-	l := len(in)
-	switch l {
-	case 0:
-		return ""
-	default:
-		*s = in[1:]
-		return in[0:1]
+	_, l := utf8.DecodeRuneInString(in)
+	*s = in[l:]
+	return in[0:l]
+}
+
+// the first grapheme cluster (what is seen as a single char)
+func firstGrapheme(s string) string {
+	cl, _, _, _ := uniseg.FirstGraphemeClusterInString(s, -1)
+	return cl
+}
+
+// the last grapheme cluster (what is seen as a single char)
+func lastGrapheme(s string) (cl string) {
+	state := -1
+	for len(s) > 0 {
+		cl, s, _, state = uniseg.FirstGraphemeClusterInString(s, state)
 	}
+	return cl
 }